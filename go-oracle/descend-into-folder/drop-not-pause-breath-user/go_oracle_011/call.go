@@ -0,0 +1,673 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/godror/godror"
+)
+
+// plsql_argument is a top-level row of ALL_ARGUMENTS. Records and objects
+// carry their fields, from data_level 1 rows or the type attribute views.
+type plsql_argument struct {
+	name         string // "" for the result of a function
+	data_type    string
+	in_out       string // IN, OUT or IN/OUT
+	defaulted    bool
+	type_owner   string
+	type_name    string
+	type_subname string
+	fields       []plsql_field
+}
+
+type plsql_field struct {
+	name      string
+	data_type string
+}
+
+// plsql_subprogram is one standalone or packaged procedure or function; each
+// overload is a separate subprogram.
+type plsql_subprogram struct {
+	owner        string
+	package_name string
+	name         string
+	overload     string
+	args         []*plsql_argument
+	result       *plsql_argument // nil for a procedure
+}
+
+func (a *plsql_argument) String() string {
+	t := a.data_type
+	if a.type_name != "" {
+		t = strings.Trim(a.type_owner+"."+a.type_name+"."+a.type_subname, ".")
+	}
+	if a.name == "" {
+		return t
+	}
+	mode := strings.ReplaceAll(a.in_out, "/", " ")
+	if a.defaulted {
+		return fmt.Sprintf("%s %s %s DEFAULT", a.name, mode, t)
+	}
+	return fmt.Sprintf("%s %s %s", a.name, mode, t)
+}
+
+// String is the signature, e.g. APP.UTIL.F(P_IN IN VARCHAR2) RETURN NUMBER.
+func (s *plsql_subprogram) String() string {
+	var args []string
+	for _, a := range s.args {
+		args = append(args, a.String())
+	}
+	name := s.owner + "." + s.name
+	if s.package_name != "" {
+		name = s.owner + "." + s.package_name + "." + s.name
+	}
+	sig := fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+	if s.result != nil {
+		sig += " RETURN " + s.result.String()
+	}
+	if s.overload != "" {
+		sig += " -- overload " + s.overload
+	}
+	return sig
+}
+
+func (s *plsql_subprogram) qualified_name() string {
+	parts := []string{quote_identifier(s.owner)}
+	if s.package_name != "" {
+		parts = append(parts, quote_identifier(s.package_name))
+	}
+	return strings.Join(append(parts, quote_identifier(s.name)), ".")
+}
+
+// argument_kind maps an ALL_ARGUMENTS data type to how it is bound; "" is
+// not supported.
+func argument_kind(data_type string) string {
+	switch data_type {
+	case "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR", "CLOB", "NCLOB", "LONG", "ROWID", "UROWID":
+		return "string"
+	case "NUMBER", "FLOAT", "BINARY_FLOAT", "BINARY_DOUBLE", "BINARY_INTEGER", "PLS_INTEGER",
+		"PL/SQL PLS INTEGER", "PL/SQL BINARY INTEGER", "INTEGER", "SMALLINT", "DECIMAL":
+		return "number"
+	case "DATE":
+		return "date"
+	case "RAW", "LONG RAW", "BLOB":
+		return "raw"
+	case "PL/SQL BOOLEAN", "BOOLEAN":
+		return "boolean"
+	case "REF CURSOR":
+		return "cursor"
+	case "PL/SQL RECORD", "OBJECT":
+		return "record"
+	}
+	if strings.HasPrefix(data_type, "TIMESTAMP") {
+		return "date"
+	}
+	return ""
+}
+
+// describe_subprograms reads the overloads of owner.[package.]name from
+// ALL_ARGUMENTS.
+func describe_subprograms(ctx context.Context, db *sql.DB, owner, package_name, name string) ([]*plsql_subprogram, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT subprogram_id, overload, argument_name, position, data_level, data_type, in_out,
+		       defaulted, type_owner, type_name, type_subname
+		FROM   all_arguments
+		WHERE  owner = :1
+		  AND  object_name = :2
+		  AND  NVL(package_name, ' ') = NVL(:3, ' ')
+		  AND  data_level <= 1
+		ORDER BY subprogram_id, sequence`, owner, name, package_name)
+	if err != nil {
+		return nil, fmt.Errorf("query all_arguments failed: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []*plsql_subprogram
+	ids := map[int]*plsql_subprogram{}
+	var last *plsql_argument
+	for rows.Next() {
+		var id, position, level int
+		var overload, arg_name, data_type, in_out, defaulted, type_owner, type_name, type_subname sql.NullString
+		if err := rows.Scan(&id, &overload, &arg_name, &position, &level, &data_type, &in_out,
+			&defaulted, &type_owner, &type_name, &type_subname); err != nil {
+			return nil, err
+		}
+		s := ids[id]
+		if s == nil {
+			s = &plsql_subprogram{owner: owner, package_name: package_name, name: name, overload: overload.String}
+			ids[id] = s
+			subs = append(subs, s)
+		}
+		if level == 1 {
+			if last != nil {
+				last.fields = append(last.fields, plsql_field{name: arg_name.String, data_type: data_type.String})
+			}
+			continue
+		}
+		// a procedure without parameters has one row without a data type
+		if !data_type.Valid {
+			last = nil
+			continue
+		}
+		last = &plsql_argument{name: arg_name.String, data_type: data_type.String, in_out: in_out.String,
+			defaulted: defaulted.String == "Y", type_owner: type_owner.String,
+			type_name: type_name.String, type_subname: type_subname.String}
+		if position == 0 && !arg_name.Valid {
+			s.result = last
+		} else {
+			s.args = append(s.args, last)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, s := range subs {
+		for _, a := range append([]*plsql_argument{s.result}, s.args...) {
+			if a != nil && argument_kind(a.data_type) == "record" && len(a.fields) == 0 {
+				if a.fields, err = composite_fields(ctx, db, a); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return subs, nil
+}
+
+// composite_fields reads the fields of a record or object type when
+// ALL_ARGUMENTS has no data_level 1 rows for it (18c and later): package
+// record types from ALL_PLSQL_TYPE_ATTRS, object types from ALL_TYPE_ATTRS
+// and %ROWTYPE records from ALL_TAB_COLUMNS.
+func composite_fields(ctx context.Context, db *sql.DB, a *plsql_argument) ([]plsql_field, error) {
+	var query string
+	var args []any
+	switch {
+	case a.data_type == "OBJECT":
+		query = "SELECT attr_name, attr_type_name FROM all_type_attrs WHERE owner = :1 AND type_name = :2 ORDER BY attr_no"
+		args = []any{a.type_owner, a.type_name}
+	case a.type_subname != "":
+		query = `SELECT attr_name, attr_type_name FROM all_plsql_type_attrs
+			WHERE owner = :1 AND package_name = :2 AND type_name = :3 ORDER BY attr_no`
+		args = []any{a.type_owner, a.type_name, a.type_subname}
+	default:
+		query = "SELECT column_name, data_type FROM all_tab_columns WHERE owner = :1 AND table_name = :2 ORDER BY column_id"
+		args = []any{a.type_owner, a.type_name}
+	}
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("describe %s failed: %w", a, err)
+	}
+	defer rows.Close()
+	var fields []plsql_field
+	for rows.Next() {
+		var f plsql_field
+		var data_type sql.NullString
+		if err := rows.Scan(&f.name, &data_type); err != nil {
+			return nil, err
+		}
+		f.data_type = data_type.String
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+// local_type is the PL/SQL type used to declare a local variable of a record
+// or object argument.
+func local_type(a *plsql_argument) string {
+	switch {
+	case a.data_type == "OBJECT":
+		return quote_identifier(a.type_owner) + "." + quote_identifier(a.type_name)
+	case a.type_subname != "":
+		return quote_identifier(a.type_owner) + "." + quote_identifier(a.type_name) + "." + quote_identifier(a.type_subname)
+	}
+	return quote_identifier(a.type_owner) + "." + quote_identifier(a.type_name) + "%ROWTYPE"
+}
+
+// find_subprograms resolves a call target: OWNER.PACKAGE.NAME, OWNER.NAME
+// or PACKAGE.NAME (in default_owner), or NAME in default_owner.
+func find_subprograms(ctx context.Context, db *sql.DB, target, default_owner string) ([]*plsql_subprogram, error) {
+	var parts []string
+	for _, p := range strings.Split(target, ".") {
+		id, err := check_identifier("subprogram", p)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, id)
+	}
+	var candidates [][3]string // owner, package, name
+	switch len(parts) {
+	case 1:
+		candidates = [][3]string{{default_owner, "", parts[0]}}
+	case 2:
+		candidates = [][3]string{{parts[0], "", parts[1]}, {default_owner, parts[0], parts[1]}}
+	case 3:
+		candidates = [][3]string{{parts[0], parts[1], parts[2]}}
+	default:
+		return nil, fmt.Errorf("expected [owner.][package.]name, got %q", target)
+	}
+	for _, c := range candidates {
+		subs, err := describe_subprograms(ctx, db, c[0], c[1], c[2])
+		if err != nil || len(subs) > 0 {
+			return subs, err
+		}
+	}
+	return nil, fmt.Errorf("%s not found in ALL_ARGUMENTS (owner %s)", target, default_owner)
+}
+
+// choose_overload picks the overload whose parameters match the given
+// argument names: every name must exist as an IN or IN OUT parameter and
+// every IN parameter without a default must be given.
+func choose_overload(subs []*plsql_subprogram, values map[string]string, overload string) (*plsql_subprogram, error) {
+	var matches []*plsql_subprogram
+	var reasons []string
+	for _, s := range subs {
+		if overload != "" {
+			if s.overload == overload {
+				return s, nil
+			}
+			continue
+		}
+		if reason := overload_mismatch(s, values); reason != "" {
+			reasons = append(reasons, fmt.Sprintf("  %s: %s", s, reason))
+			continue
+		}
+		matches = append(matches, s)
+	}
+	switch {
+	case overload != "":
+		return nil, fmt.Errorf("no overload %s", overload)
+	case len(matches) == 1:
+		return matches[0], nil
+	case len(matches) == 0:
+		return nil, fmt.Errorf("the arguments match no signature:\n%s", strings.Join(reasons, "\n"))
+	}
+	var sigs []string
+	for _, s := range matches {
+		sigs = append(sigs, "  "+s.String())
+	}
+	return nil, fmt.Errorf("the arguments match %d overloads; use -overload:\n%s", len(matches), strings.Join(sigs, "\n"))
+}
+
+func overload_mismatch(s *plsql_subprogram, values map[string]string) string {
+	params := map[string]*plsql_argument{}
+	for _, a := range s.args {
+		params[a.name] = a
+	}
+	var names []string
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a, ok := params[name]
+		switch {
+		case !ok:
+			return "no parameter " + name
+		case a.in_out == "OUT":
+			return name + " is an OUT parameter"
+		case argument_kind(a.data_type) == "record":
+			return name + " is a record; record arguments are not supported"
+		}
+	}
+	for _, a := range s.args {
+		if _, given := values[a.name]; !given && a.in_out == "IN" && !a.defaulted {
+			return "missing " + a.name
+		}
+	}
+	return ""
+}
+
+// in_value converts the text of an --arg to the bind value for kind. An
+// empty value is NULL.
+func in_value(kind, text string) (any, error) {
+	switch kind {
+	case "string":
+		return text, nil
+	case "number":
+		if text != "" {
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, fmt.Errorf("%q is not a number", text)
+			}
+		}
+		return godror.Number(text), nil
+	case "date":
+		if text == "" {
+			return godror.NullTime{}, nil
+		}
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.ParseInLocation(layout, text, time.Local); err == nil {
+				return godror.NullTime{Time: t, Valid: true}, nil
+			}
+		}
+		return nil, fmt.Errorf("%q is not a date (YYYY-MM-DD[ HH24:MI:SS] or RFC 3339)", text)
+	case "raw":
+		b, err := hex.DecodeString(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not hex: %w", text, err)
+		}
+		return b, nil
+	case "boolean":
+		if text == "" {
+			return nil, nil
+		}
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a boolean", text)
+		}
+		if b {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return nil, fmt.Errorf("unsupported type")
+}
+
+// out_dest returns a destination for an OUT bind of kind, holding the IN
+// value for IN OUT.
+func out_dest(kind string, in any) any {
+	switch kind {
+	case "string":
+		s, _ := in.(string)
+		return &s
+	case "number":
+		n, _ := in.(godror.Number)
+		return &n
+	case "date":
+		t, _ := in.(godror.NullTime)
+		return &t
+	case "raw":
+		b, _ := in.([]byte)
+		return &b
+	case "cursor":
+		return new(driver.Rows)
+	}
+	// booleans are converted to a number in the block
+	n := godror.Number("")
+	if i, ok := in.(int); ok {
+		n = godror.Number(strconv.Itoa(i))
+	}
+	return &n
+}
+
+// call_output is an OUT value to print after the call.
+type call_output struct {
+	label string
+	kind  string
+	dest  any
+}
+
+// plsql_call is the anonymous block that calls a subprogram. Booleans and
+// records cannot be bound, so they go through local variables: booleans are
+// passed as 1/0 and records are returned field by field. Binds are named
+// (:b1, :b2, ...) because the declarations, the call and the assignments
+// after it are generated in a different order than they appear.
+type plsql_call struct {
+	block   string
+	args    []any
+	outputs []call_output
+}
+
+func build_call(s *plsql_subprogram, values map[string]string) (*plsql_call, error) {
+	c := &plsql_call{}
+	var decls, after []string
+	bind := func(v any) string {
+		name := fmt.Sprintf("b%d", len(c.args)+1)
+		c.args = append(c.args, sql.Named(name, v))
+		return ":" + name
+	}
+	output := func(label, kind string, in any, in_out bool) string {
+		dest := out_dest(kind, in)
+		c.outputs = append(c.outputs, call_output{label: label, kind: kind, dest: dest})
+		return bind(sql.Out{Dest: dest, In: in_out})
+	}
+	// read_local copies a boolean or record local variable to OUT binds
+	read_local := func(a *plsql_argument, label, local string) error {
+		if argument_kind(a.data_type) == "boolean" {
+			after = append(after, fmt.Sprintf("  %s := CASE WHEN %s THEN 1 WHEN NOT %s THEN 0 END;",
+				output(label, "boolean", nil, false), local, local))
+			return nil
+		}
+		for _, f := range a.fields {
+			switch kind := argument_kind(f.data_type); kind {
+			case "", "record", "cursor", "boolean":
+				return fmt.Errorf("%s.%s: type %s is not supported", label, f.name, f.data_type)
+			default:
+				after = append(after, fmt.Sprintf("  %s := %s.%s;", output(label+"."+f.name, kind, nil, false),
+					local, quote_identifier(f.name)))
+			}
+		}
+		return nil
+	}
+
+	call := s.qualified_name()
+	if r := s.result; r != nil {
+		switch kind := argument_kind(r.data_type); kind {
+		case "":
+			return nil, fmt.Errorf("result type %s is not supported", r.data_type)
+		case "boolean", "record":
+			t := "BOOLEAN"
+			if kind == "record" {
+				t = local_type(r)
+			}
+			decls = append(decls, fmt.Sprintf("  l_result %s;", t))
+			call = "l_result := " + call
+			if err := read_local(r, "RETURN", "l_result"); err != nil {
+				return nil, err
+			}
+		default:
+			call = output("RETURN", kind, nil, false) + " := " + call
+		}
+	}
+
+	var params []string
+	for i, a := range s.args {
+		kind := argument_kind(a.data_type)
+		if kind == "" {
+			return nil, fmt.Errorf("%s: type %s is not supported", a.name, a.data_type)
+		}
+		text, given := values[a.name]
+		if !given && a.in_out == "IN" {
+			continue // left to its default
+		}
+		var in any
+		if given {
+			v, err := in_value(kind, text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", a.name, err)
+			}
+			in = v
+		}
+		var actual string
+		switch {
+		case kind == "boolean" || kind == "record":
+			actual = fmt.Sprintf("l_%d", i+1)
+			decl := fmt.Sprintf("  %s BOOLEAN", actual)
+			if kind == "record" {
+				decl = fmt.Sprintf("  %s %s", actual, local_type(a))
+			} else if given {
+				decl += fmt.Sprintf(" := CASE %s WHEN 1 THEN TRUE WHEN 0 THEN FALSE END", bind(in))
+			}
+			decls = append(decls, decl+";")
+			if a.in_out != "IN" {
+				if err := read_local(a, a.name, actual); err != nil {
+					return nil, err
+				}
+			}
+		case a.in_out == "IN":
+			actual = bind(in)
+		default:
+			actual = output(a.name, kind, in, a.in_out == "IN/OUT")
+		}
+		params = append(params, fmt.Sprintf("%s => %s", quote_identifier(a.name), actual))
+	}
+	if len(params) > 0 {
+		call += "(" + strings.Join(params, ", ") + ")"
+	}
+
+	var b strings.Builder
+	if len(decls) > 0 {
+		b.WriteString("DECLARE\n" + strings.Join(decls, "\n") + "\n")
+	}
+	b.WriteString("BEGIN\n  " + call + ";\n")
+	for _, a := range after {
+		b.WriteString(a + "\n")
+	}
+	b.WriteString("END;")
+	c.block = b.String()
+	return c, nil
+}
+
+// output_value renders an OUT value for printing; REF CURSORs are printed
+// separately.
+func output_value(dest any) string {
+	var v any
+	switch d := dest.(type) {
+	case *string:
+		v = *d
+	case *godror.Number:
+		v = string(*d)
+	case *godror.NullTime:
+		if !d.Valid {
+			return "NULL"
+		}
+		v = d.Time
+	case *[]byte:
+		v = *d
+	}
+	s, null := format_value(v)
+	if null {
+		return "NULL"
+	}
+	return s
+}
+
+// print_cursor prints the rows of a REF CURSOR as an aligned table.
+func print_cursor(rset driver.Rows) error {
+	defer rset.Close()
+	cols := rset.Columns()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(cols, "\t"))
+	vals := make([]driver.Value, len(cols))
+	n := 0
+	for {
+		if err := rset.Next(vals); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		n++
+		if n > max_printed_rows {
+			continue
+		}
+		cells := make([]string, len(vals))
+		for i, v := range vals {
+			if s, null := format_value(v); !null {
+				cells[i] = s
+			}
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	w.Flush()
+	if n > max_printed_rows {
+		fmt.Printf("... %d more row(s)\n", n-max_printed_rows)
+	}
+	fmt.Printf("📊 %d row(s)\n", n)
+	return nil
+}
+
+func run_call(args []string) error {
+	fs := flag.NewFlagSet("call", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	values := define_flags{}
+	fs.Var(values, "arg", "parameter name=value (repeatable; empty value is NULL)")
+	owner := fs.String("owner", "", "schema of unqualified names (default: the session's current schema)")
+	overload := fs.String("overload", "", "ALL_ARGUMENTS.OVERLOAD to call when the arguments match several")
+	describe := fs.Bool("describe", false, "only print the signatures and the generated block")
+	output := add_output_flags(fs)
+	positional := parse_interspersed(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: call [flags] [owner.][package.]name -arg p_in=value ...")
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	default_owner := ""
+	if *owner != "" {
+		if default_owner, err = check_identifier("owner", *owner); err != nil {
+			return err
+		}
+	} else if err := db.QueryRowContext(ctx,
+		"SELECT SYS_CONTEXT('USERENV','CURRENT_SCHEMA') FROM dual").Scan(&default_owner); err != nil {
+		return fmt.Errorf("could not read current schema: %w", err)
+	}
+
+	subs, err := find_subprograms(ctx, db, positional[0], default_owner)
+	if err != nil {
+		return err
+	}
+	if *describe {
+		for _, s := range subs {
+			fmt.Printf("🔎 %s\n", s)
+		}
+	}
+	s, err := choose_overload(subs, values, *overload)
+	if err != nil {
+		return err
+	}
+	call, err := build_call(s, values)
+	if err != nil {
+		return fmt.Errorf("%s: %w", s, err)
+	}
+	if *describe {
+		fmt.Println(call.block)
+		return nil
+	}
+
+	if err := output.enable(ctx, db); err != nil {
+		return err
+	}
+	fmt.Printf("📞 %s\n", s)
+	_, err = db.ExecContext(ctx, call.block, call.args...)
+	if out_err := output.collect(ctx, db, s.qualified_name()); out_err != nil && err == nil {
+		err = out_err
+	}
+	if err != nil {
+		return err
+	}
+	for _, o := range call.outputs {
+		if o.kind != "cursor" {
+			v := output_value(o.dest)
+			if o.kind == "boolean" && v != "NULL" {
+				v = map[string]string{"1": "TRUE", "0": "FALSE"}[v]
+			}
+			fmt.Printf("📤 %s = %s\n", o.label, v)
+			continue
+		}
+		rset := *o.dest.(*driver.Rows)
+		if rset == nil {
+			fmt.Printf("📤 %s = NULL\n", o.label)
+			continue
+		}
+		fmt.Printf("📤 %s:\n", o.label)
+		if err := print_cursor(rset); err != nil {
+			return fmt.Errorf("%s: %w", o.label, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,662 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const default_codegen_corpus = "testdata/codegen"
+
+func run_codegen(args []string) error {
+	return run_group("codegen", []command{
+		{"plsql", "generate Go wrappers for packages, procedures and functions of -owner", run_codegen_plsql},
+		{"tables", "generate Go structs with insert, get, update and delete for tables of -owner", run_codegen_tables},
+		{"check", "generate from the YAML fixtures of testdata/codegen and compare with the .golden files", run_codegen_check},
+	}, args)
+}
+
+// codegen_fixture is the YAML form of what the generator reads from the
+// dictionary. -save writes it so that the output can be regenerated and
+// checked against golden files without a database.
+type codegen_fixture struct {
+	Go_package  string               `yaml:"go_package"`
+	Subprograms []fixture_subprogram `yaml:"subprograms,omitempty"`
+	Tables      []*table_def         `yaml:"tables,omitempty"`
+}
+
+type fixture_subprogram struct {
+	Owner    string             `yaml:"owner"`
+	Package  string             `yaml:"package,omitempty"`
+	Name     string             `yaml:"name"`
+	Overload string             `yaml:"overload,omitempty"`
+	Result   *fixture_argument  `yaml:"result,omitempty"`
+	Args     []fixture_argument `yaml:"args,omitempty"`
+}
+
+type fixture_argument struct {
+	Name         string          `yaml:"name,omitempty"`
+	Data_type    string          `yaml:"data_type"`
+	In_out       string          `yaml:"in_out,omitempty"`
+	Defaulted    bool            `yaml:"defaulted,omitempty"`
+	Type_owner   string          `yaml:"type_owner,omitempty"`
+	Type_name    string          `yaml:"type_name,omitempty"`
+	Type_subname string          `yaml:"type_subname,omitempty"`
+	Fields       []fixture_field `yaml:"fields,omitempty"`
+}
+
+type fixture_field struct {
+	Name      string `yaml:"name"`
+	Data_type string `yaml:"data_type"`
+}
+
+func (a *plsql_argument) fixture() *fixture_argument {
+	f := &fixture_argument{Name: a.name, Data_type: a.data_type, In_out: a.in_out, Defaulted: a.defaulted,
+		Type_owner: a.type_owner, Type_name: a.type_name, Type_subname: a.type_subname}
+	for _, fl := range a.fields {
+		f.Fields = append(f.Fields, fixture_field{Name: fl.name, Data_type: fl.data_type})
+	}
+	return f
+}
+
+func (f *fixture_argument) argument() *plsql_argument {
+	in_out := f.In_out
+	if in_out == "" && f.Name != "" {
+		in_out = "IN"
+	}
+	a := &plsql_argument{name: f.Name, data_type: f.Data_type, in_out: in_out, defaulted: f.Defaulted,
+		type_owner: f.Type_owner, type_name: f.Type_name, type_subname: f.Type_subname}
+	for _, fl := range f.Fields {
+		a.fields = append(a.fields, plsql_field{name: fl.Name, data_type: fl.Data_type})
+	}
+	return a
+}
+
+func fixture_of(go_package string, subs []*plsql_subprogram) codegen_fixture {
+	fx := codegen_fixture{Go_package: go_package}
+	for _, s := range subs {
+		fs := fixture_subprogram{Owner: s.owner, Package: s.package_name, Name: s.name, Overload: s.overload}
+		if s.result != nil {
+			fs.Result = s.result.fixture()
+		}
+		for _, a := range s.args {
+			fs.Args = append(fs.Args, *a.fixture())
+		}
+		fx.Subprograms = append(fx.Subprograms, fs)
+	}
+	return fx
+}
+
+func (fx codegen_fixture) subprograms() []*plsql_subprogram {
+	var subs []*plsql_subprogram
+	for _, fs := range fx.Subprograms {
+		s := &plsql_subprogram{owner: fs.Owner, package_name: fs.Package, name: fs.Name, overload: fs.Overload}
+		if fs.Result != nil {
+			s.result = fs.Result.argument()
+		}
+		for i := range fs.Args {
+			s.args = append(s.args, fs.Args[i].argument())
+		}
+		subs = append(subs, s)
+	}
+	return subs
+}
+
+// codegen_subprograms describes the named packages, packaged subprograms
+// (PACKAGE.NAME) and standalone procedures and functions of owner. A
+// package expands to its subprograms in ALL_PROCEDURES order.
+func codegen_subprograms(ctx context.Context, db *sql.DB, owner string, names []string) ([]*plsql_subprogram, error) {
+	var subs []*plsql_subprogram
+	describe := func(pkg, name string) error {
+		found, err := describe_subprograms(ctx, db, owner, pkg, name)
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			// no parameters and no result: ALL_ARGUMENTS may have no row
+			found = []*plsql_subprogram{{owner: owner, package_name: pkg, name: name}}
+		}
+		subs = append(subs, found...)
+		return nil
+	}
+
+	for _, n := range names {
+		pkg, name, packaged := strings.Cut(n, ".")
+		if !packaged {
+			name, pkg = pkg, ""
+		}
+		var err error
+		if pkg != "" {
+			if pkg, err = check_identifier("package", pkg); err != nil {
+				return nil, err
+			}
+		}
+		if name, err = check_identifier("subprogram", name); err != nil {
+			return nil, err
+		}
+		if packaged {
+			if err := describe(pkg, name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		var obj_type string
+		err = db.QueryRowContext(ctx, `
+			SELECT object_type FROM all_procedures
+			WHERE  owner = :1 AND object_name = :2 AND procedure_name IS NULL
+			  AND  object_type IN ('PACKAGE', 'PROCEDURE', 'FUNCTION')`, owner, name).Scan(&obj_type)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("%s.%s is not a package, procedure or function", owner, name)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("query all_procedures failed: %w", err)
+		}
+		if obj_type != "PACKAGE" {
+			if err := describe("", name); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		procs, err := package_subprogram_names(ctx, db, owner, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range procs {
+			if err := describe(name, p); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return subs, nil
+}
+
+func package_subprogram_names(ctx context.Context, db *sql.DB, owner, pkg string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT procedure_name
+		FROM   all_procedures
+		WHERE  owner = :1 AND object_name = :2 AND procedure_name IS NOT NULL
+		GROUP BY procedure_name
+		ORDER BY MIN(subprogram_id)`, owner, pkg)
+	if err != nil {
+		return nil, fmt.Errorf("query all_procedures failed: %w", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+// ---- Go source generation ----
+
+var go_initialisms = map[string]bool{"ID": true, "URL": true, "SQL": true, "JSON": true, "XML": true, "UUID": true, "HTTP": true}
+
+// go_name turns an Oracle name into a Go identifier: GET_LOWER_CASE becomes
+// GetLowerCase, or getLowerCase when exported is false.
+func go_name(s string, exported bool) string {
+	var b strings.Builder
+	for i, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '$' || r == '#' }) {
+		up := strings.ToUpper(part)
+		switch {
+		case i == 0 && !exported:
+			b.WriteString(strings.ToLower(part))
+		case go_initialisms[up]:
+			b.WriteString(up)
+		default:
+			b.WriteString(up[:1] + strings.ToLower(up[1:]))
+		}
+	}
+	name := b.String()
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "X" + name
+	}
+	return name
+}
+
+// go_reserved are Go keywords and the names the generated functions use
+// themselves; parameters with these names get a trailing underscore.
+var go_reserved = map[string]bool{
+	"break": true, "case": true, "chan": true, "const": true, "continue": true, "default": true,
+	"defer": true, "else": true, "fallthrough": true, "for": true, "func": true, "go": true,
+	"goto": true, "if": true, "import": true, "interface": true, "map": true, "package": true,
+	"range": true, "return": true, "select": true, "struct": true, "switch": true, "type": true,
+	"var": true, "ctx": true, "db": true, "err": true, "result": true,
+}
+
+func go_param_name(s string) string {
+	n := go_name(s, false)
+	if go_reserved[n] {
+		n += "_"
+	}
+	return n
+}
+
+// go_type is the Go type of a scalar argument kind.
+func go_type(kind, data_type string) string {
+	switch kind {
+	case "string":
+		return "string"
+	case "number":
+		switch {
+		case strings.Contains(data_type, "INTEGER") || data_type == "SMALLINT":
+			return "int64"
+		case strings.HasPrefix(data_type, "BINARY_") || data_type == "FLOAT":
+			return "float64"
+		}
+		return "godror.Number"
+	case "date":
+		return "time.Time"
+	case "raw":
+		return "[]byte"
+	case "boolean":
+		return "bool"
+	case "cursor":
+		return "*sql.Rows"
+	}
+	return ""
+}
+
+// go_generator collects the functions and record structs of one file.
+type go_generator struct {
+	imports map[string]bool
+	structs map[string]string // Go name -> declaration
+	funcs   []string
+}
+
+func new_go_generator() *go_generator {
+	return &go_generator{imports: map[string]bool{"context": true, "database/sql": true}, structs: map[string]string{}}
+}
+
+func (g *go_generator) use_type(t string) {
+	switch {
+	case strings.HasPrefix(t, "godror."):
+		g.imports["github.com/godror/godror"] = true
+	case t == "time.Time":
+		g.imports["time"] = true
+	}
+}
+
+// record_struct declares (once) the Go struct of a record or object type
+// and returns its name.
+func (g *go_generator) record_struct(a *plsql_argument, fallback string) (string, error) {
+	name := fallback
+	if a.type_name != "" {
+		name = go_name(a.type_name+"_"+a.type_subname, true)
+	}
+	if _, done := g.structs[name]; done {
+		return name, nil
+	}
+	if len(a.fields) == 0 {
+		return "", fmt.Errorf("%s: no fields for type %s", fallback, a)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s mirrors %s.\ntype %s struct {\n", name, strings.Trim(a.type_owner+"."+a.type_name+"."+a.type_subname, "."), name)
+	for _, f := range a.fields {
+		t := go_type(argument_kind(f.data_type), f.data_type)
+		if t == "" || t == "bool" || t == "*sql.Rows" {
+			return "", fmt.Errorf("%s.%s: type %s is not supported", name, f.name, f.data_type)
+		}
+		g.use_type(t)
+		fmt.Fprintf(&b, "\t%s %s // %s\n", go_name(f.name, true), t, f.data_type)
+	}
+	b.WriteString("}\n")
+	g.structs[name] = b.String()
+	return name, nil
+}
+
+// go_func generates the wrapper of one subprogram. The PL/SQL block binds
+// by name; booleans travel as 1/0 and records field by field through local
+// variables, as in the call command.
+func (g *go_generator) go_func(s *plsql_subprogram, func_name string) error {
+	var params, results, pre, post, binds, decls, after, actuals []string
+	out_var := func(name string) string { return name + "Out" }
+
+	// read_local assigns a boolean or record local variable to OUT binds
+	// after the call; target is the Go variable receiving it.
+	read_local := func(a *plsql_argument, target, local, bind string) error {
+		if argument_kind(a.data_type) == "boolean" {
+			num := out_var(target)
+			pre = append(pre, fmt.Sprintf("var %s int64", num))
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, sql.Out{Dest: &%s})", bind, num))
+			after = append(after, fmt.Sprintf("  :%s := CASE WHEN %s THEN 1 WHEN NOT %s THEN 0 END;", bind, local, local))
+			post = append(post, fmt.Sprintf("%s = %s == 1", target, num))
+			return nil
+		}
+		for _, f := range a.fields {
+			fb := bind + "_" + strings.ToLower(f.name)
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, sql.Out{Dest: &%s.%s})", fb, target, go_name(f.name, true)))
+			after = append(after, fmt.Sprintf("  :%s := %s.%s;", fb, local, quote_identifier(f.name)))
+		}
+		return nil
+	}
+
+	call := s.qualified_name()
+	if r := s.result; r != nil {
+		kind := argument_kind(r.data_type)
+		t := go_type(kind, r.data_type)
+		switch kind {
+		case "":
+			return fmt.Errorf("%s: result type %s is not supported", s, r.data_type)
+		case "record":
+			name, err := g.record_struct(r, func_name+"Result")
+			if err != nil {
+				return err
+			}
+			t = name
+		}
+		g.use_type(t)
+		results = append(results, "result "+t)
+		switch kind {
+		case "boolean", "record":
+			local_t := "BOOLEAN"
+			if kind == "record" {
+				local_t = local_type(r)
+			}
+			decls = append(decls, fmt.Sprintf("  l_result %s;", local_t))
+			call = "l_result := " + call
+			if err := read_local(r, "result", "l_result", "b_result"); err != nil {
+				return err
+			}
+		case "cursor":
+			pre = append(pre, "var resultCursor driver.Rows")
+			binds = append(binds, `sql.Named("b_result", sql.Out{Dest: &resultCursor})`)
+			call = ":b_result := " + call
+			post = append(post, wrap_cursor("result", "resultCursor"))
+		default:
+			binds = append(binds, `sql.Named("b_result", sql.Out{Dest: &result})`)
+			call = ":b_result := " + call
+		}
+	}
+
+	for _, a := range s.args {
+		kind := argument_kind(a.data_type)
+		v := go_param_name(a.name)
+		bind := "b_" + strings.ToLower(a.name)
+		local := "l_" + strings.ToLower(a.name)
+		t := go_type(kind, a.data_type)
+		switch {
+		case kind == "":
+			return fmt.Errorf("%s: %s: type %s is not supported", s, a.name, a.data_type)
+		case kind == "record" && a.in_out != "OUT":
+			return fmt.Errorf("%s: %s: record arguments are only supported as OUT", s, a.name)
+		case kind == "cursor" && a.in_out != "OUT":
+			return fmt.Errorf("%s: %s: cursor arguments are only supported as OUT", s, a.name)
+		case kind == "record":
+			name, err := g.record_struct(a, func_name+go_name(a.name, true))
+			if err != nil {
+				return err
+			}
+			t = name
+		}
+		g.use_type(t)
+		if a.in_out != "OUT" {
+			params = append(params, v+" "+t)
+		}
+		if a.in_out != "IN" {
+			if a.in_out == "OUT" {
+				results = append(results, v+" "+t)
+			} else {
+				results = append(results, v+"Result "+t)
+				pre = append(pre, fmt.Sprintf("%sResult = %s", v, v))
+				v += "Result"
+			}
+		}
+
+		actual := ":" + bind
+		switch {
+		case kind == "boolean" || kind == "record":
+			actual = local
+			decl := fmt.Sprintf("  %s BOOLEAN", local)
+			if kind == "record" {
+				decl = fmt.Sprintf("  %s %s", local, local_type(a))
+			} else if a.in_out != "OUT" {
+				num := v + "Num"
+				pre = append(pre, fmt.Sprintf("var %s int64\nif %s {\n%s = 1\n}", num, v, num))
+				binds = append(binds, fmt.Sprintf("sql.Named(%q, %s)", bind+"_in", num))
+				decl += fmt.Sprintf(" := CASE :%s_in WHEN 1 THEN TRUE WHEN 0 THEN FALSE END", bind)
+			}
+			decls = append(decls, decl+";")
+			if a.in_out != "IN" {
+				if err := read_local(a, v, local, bind); err != nil {
+					return err
+				}
+			}
+		case kind == "cursor":
+			pre = append(pre, fmt.Sprintf("var %sCursor driver.Rows", v))
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, sql.Out{Dest: &%sCursor})", bind, v))
+			post = append(post, wrap_cursor(v, v+"Cursor"))
+		case a.in_out == "IN":
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, %s)", bind, v))
+		default:
+			in_out := ""
+			if a.in_out == "IN/OUT" {
+				in_out = ", In: true"
+			}
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, sql.Out{Dest: &%s%s})", bind, v, in_out))
+		}
+		actuals = append(actuals, fmt.Sprintf("%s => %s", quote_identifier(a.name), actual))
+	}
+	if len(actuals) > 0 {
+		call += "(" + strings.Join(actuals, ", ") + ")"
+	}
+
+	var block strings.Builder
+	if len(decls) > 0 {
+		block.WriteString("DECLARE\n" + strings.Join(decls, "\n") + "\n")
+	}
+	block.WriteString("BEGIN\n  " + call + ";\n")
+	for _, a := range after {
+		block.WriteString(a + "\n")
+	}
+	block.WriteString("END;")
+
+	kind := "PROCEDURE"
+	if s.result != nil {
+		kind = "FUNCTION"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s calls %s.\n//\n//\t%s %s\n", func_name, strings.SplitN(s.String(), "(", 2)[0], kind, s)
+	fmt.Fprintf(&b, "func %s(ctx context.Context, db DB", func_name)
+	for _, p := range params {
+		b.WriteString(", " + p)
+	}
+	fmt.Fprintf(&b, ") (%s) {\n", strings.Join(append(results, "err error"), ", "))
+	for _, p := range pre {
+		b.WriteString(p + "\n")
+	}
+	fmt.Fprintf(&b, "_, err = db.ExecContext(ctx, `%s`", block.String())
+	for _, bd := range binds {
+		b.WriteString(",\n" + bd)
+	}
+	b.WriteString(")\n")
+	if len(post) > 0 {
+		b.WriteString("if err != nil {\nreturn\n}\n" + strings.Join(post, "\n") + "\n")
+	}
+	b.WriteString("return\n}\n")
+	g.funcs = append(g.funcs, b.String())
+	return nil
+}
+
+// wrap_cursor turns a REF CURSOR bind into *sql.Rows; a NULL cursor stays nil.
+func wrap_cursor(target, cursor string) string {
+	return fmt.Sprintf("if %s != nil {\nif %s, err = godror.WrapRows(ctx, db, %s); err != nil {\nreturn\n}\n}", cursor, target, cursor)
+}
+
+// generate_go_file renders the Go file for subs and tables. Overloads get
+// their overload number appended to the function name.
+func generate_go_file(go_package string, subs []*plsql_subprogram, tables []*table_def) ([]byte, error) {
+	g := new_go_generator()
+	counts := map[string]int{}
+	for _, s := range subs {
+		counts[s.package_name+"."+s.name]++
+	}
+	for _, s := range subs {
+		name := go_name(s.package_name+"_"+s.name, true)
+		if counts[s.package_name+"."+s.name] > 1 {
+			name += s.overload
+		}
+		if err := g.go_func(s, name); err != nil {
+			return nil, err
+		}
+	}
+	for _, t := range tables {
+		if err := g.table_funcs(t); err != nil {
+			return nil, err
+		}
+	}
+	for _, f := range g.funcs {
+		if strings.Contains(f, "driver.Rows") {
+			g.imports["database/sql/driver"] = true
+			g.imports["github.com/godror/godror"] = true
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("// Code generated by go_oracle_011 codegen; DO NOT EDIT.\n\n")
+	fmt.Fprintf(&b, "package %s\n\nimport (\n", go_package)
+	// standard library first, then a group with the modules
+	var std, modules []string
+	for i := range g.imports {
+		if strings.Contains(strings.Split(i, "/")[0], ".") {
+			modules = append(modules, i)
+		} else {
+			std = append(std, i)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(modules)
+	for _, i := range std {
+		fmt.Fprintf(&b, "%q\n", i)
+	}
+	if len(modules) > 0 {
+		b.WriteString("\n")
+	}
+	for _, i := range modules {
+		fmt.Fprintf(&b, "%q\n", i)
+	}
+	b.WriteString(")\n\n")
+	b.WriteString("// DB is satisfied by *sql.DB, *sql.Conn and *sql.Tx.\ntype DB interface {\n" +
+		"ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)\n" +
+		"QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)\n" +
+		"QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row\n}\n\n")
+	var structs []string
+	for name := range g.structs {
+		structs = append(structs, name)
+	}
+	sort.Strings(structs)
+	for _, name := range structs {
+		b.WriteString(g.structs[name] + "\n")
+	}
+	b.WriteString(strings.Join(g.funcs, "\n"))
+
+	src, err := format.Source([]byte(b.String()))
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return src, nil
+}
+
+func run_codegen_plsql(args []string) error {
+	fs := flag.NewFlagSet("codegen plsql", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema of the packages and functions")
+	go_package := fs.String("package", "db", "Go package name of the generated file")
+	out := fs.String("out", "", "write the Go file here instead of stdout")
+	save := fs.String("save", "", "also write the described signatures as a YAML fixture (for codegen check)")
+	positional := parse_interspersed(fs, args)
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: codegen plsql -owner X [flags] <package|package.name|function>...")
+	}
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	subs, err := codegen_subprograms(ctx, db, target, positional)
+	if err != nil {
+		return err
+	}
+	return write_codegen(fixture_of(*go_package, subs), *out, *save)
+}
+
+// write_codegen writes the Go file of a fixture to out (stdout when empty)
+// and, with save set, the fixture itself.
+func write_codegen(fx codegen_fixture, out, save string) error {
+	if save != "" {
+		data, err := yaml.Marshal(fx)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(save, data, 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "📝 fixture written to %s\n", save)
+	}
+	src, err := generate_go_file(fx.Go_package, fx.subprograms(), fx.Tables)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	if err := os.WriteFile(out, src, 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "📝 %d subprogram(s) and %d table(s) written to %s\n", len(fx.Subprograms), len(fx.Tables), out)
+	return nil
+}
+
+// generate_from_fixture renders the Go file for a YAML fixture.
+func generate_from_fixture(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fx codegen_fixture
+	if err := yaml.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if fx.Go_package == "" {
+		fx.Go_package = "db"
+	}
+	return generate_go_file(fx.Go_package, fx.subprograms(), fx.Tables)
+}
+
+// codegen_corpus is the generator corpus: the Go file generated from each
+// YAML fixture is compared with <name>.golden, as TestCodegenGolden does;
+// -update regenerates the golden files.
+var codegen_corpus = corpus_spec{
+	what:    "fixture",
+	dir:     default_codegen_corpus,
+	globs:   []string{"*.yaml"},
+	out_ext: ".golden",
+	render: func(path string) (string, error) {
+		src, err := generate_from_fixture(path)
+		return string(src), err
+	},
+}
+
+func run_codegen_check(args []string) error {
+	return run_corpus_check("codegen check", codegen_corpus, args)
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// table_def is a table as codegen tables reads it from ALL_TAB_COLUMNS,
+// ALL_CONSTRAINTS and ALL_CONS_COLUMNS; it is also the fixture form.
+type table_def struct {
+	Owner       string         `yaml:"owner"`
+	Name        string         `yaml:"name"`
+	Columns     []table_column `yaml:"columns"`
+	Primary_key []string       `yaml:"primary_key,omitempty"`
+}
+
+type table_column struct {
+	Name      string `yaml:"name"`
+	Data_type string `yaml:"data_type"` // as in DDL, e.g. VARCHAR2(100 BYTE)
+	Not_null  bool   `yaml:"not_null,omitempty"`
+	Identity  string `yaml:"identity,omitempty"` // ALWAYS or BY DEFAULT
+	Virtual   bool   `yaml:"virtual,omitempty"`
+}
+
+// generated reports whether the database computes the column, so it is
+// left out of INSERT and UPDATE and read back with RETURNING INTO.
+func (c table_column) generated() bool {
+	return c.Identity != "" || c.Virtual
+}
+
+func (t *table_def) qualified_name() string {
+	return quote_identifier(t.Owner) + "." + quote_identifier(t.Name)
+}
+
+func read_table_def(ctx context.Context, db *sql.DB, owner, table string) (*table_def, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.column_name, c.data_type, c.data_length, NVL(c.char_length, 0), NVL(c.char_used, 'B'),
+		       c.data_precision, c.data_scale, c.nullable, c.identity_column, x.virtual_column,
+		       i.generation_type
+		FROM   all_tab_columns c
+		JOIN   all_tab_cols x
+		       ON x.owner = c.owner AND x.table_name = c.table_name AND x.column_name = c.column_name
+		LEFT JOIN all_tab_identity_cols i
+		       ON i.owner = c.owner AND i.table_name = c.table_name AND i.column_name = c.column_name
+		WHERE  c.owner = :1 AND c.table_name = :2
+		ORDER BY c.column_id`, owner, table)
+	if err != nil {
+		return nil, fmt.Errorf("query all_tab_columns failed: %w", err)
+	}
+	defer rows.Close()
+
+	t := &table_def{Owner: owner, Name: table}
+	for rows.Next() {
+		var c table_column
+		var data_type, char_used, nullable, identity, virtual string
+		var length, char_length int64
+		var precision, scale sql.NullInt64
+		var generation sql.NullString
+		if err := rows.Scan(&c.Name, &data_type, &length, &char_length, &char_used, &precision, &scale,
+			&nullable, &identity, &virtual, &generation); err != nil {
+			return nil, err
+		}
+		c.Data_type = sql_data_type(data_type, length, char_length, char_used, precision, scale)
+		c.Not_null = nullable == "N"
+		c.Virtual = virtual == "YES"
+		if identity == "YES" {
+			c.Identity = generation.String
+		}
+		t.Columns = append(t.Columns, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(t.Columns) == 0 {
+		return nil, fmt.Errorf("table %s.%s not found", owner, table)
+	}
+
+	pk, err := db.QueryContext(ctx, `
+		SELECT cc.column_name
+		FROM   all_constraints k
+		JOIN   all_cons_columns cc ON cc.owner = k.owner AND cc.constraint_name = k.constraint_name
+		WHERE  k.owner = :1 AND k.table_name = :2 AND k.constraint_type = 'P'
+		ORDER BY cc.position`, owner, table)
+	if err != nil {
+		return nil, fmt.Errorf("query primary key failed: %w", err)
+	}
+	defer pk.Close()
+	for pk.Next() {
+		var col string
+		if err := pk.Scan(&col); err != nil {
+			return nil, err
+		}
+		t.Primary_key = append(t.Primary_key, col)
+	}
+	return t, pk.Err()
+}
+
+// owner_tables lists the ordinary tables of owner.
+func owner_tables(ctx context.Context, db *sql.DB, owner string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT table_name FROM all_tables
+		WHERE  owner = :1 AND nested = 'NO' AND secondary = 'N' AND table_name NOT LIKE 'BIN$%'
+		ORDER BY table_name`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query all_tables failed: %w", err)
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+var type_args_re = regexp.MustCompile(`\([^)]*\)`)
+
+// column_go_type maps a column to its Go type. Nullable text, binary floats
+// and dates use the sql.Null types; godror.Number and []byte hold NULL as
+// "" and nil. TIMESTAMP WITH TIME ZONE maps to time.Time, which keeps the
+// zone.
+func column_go_type(c table_column) (string, error) {
+	base := type_args_re.ReplaceAllString(c.Data_type, "")
+	switch base {
+	case "VARCHAR2", "NVARCHAR2", "CHAR", "NCHAR", "CLOB", "NCLOB", "ROWID", "UROWID":
+		if c.Not_null {
+			return "string", nil
+		}
+		return "sql.NullString", nil
+	case "NUMBER", "FLOAT", "INTEGER":
+		return "godror.Number", nil
+	case "BINARY_FLOAT", "BINARY_DOUBLE":
+		if c.Not_null {
+			return "float64", nil
+		}
+		return "sql.NullFloat64", nil
+	case "RAW", "BLOB", "LONG RAW":
+		return "[]byte", nil
+	}
+	if base == "DATE" || strings.HasPrefix(base, "TIMESTAMP") {
+		if c.Not_null {
+			return "time.Time", nil
+		}
+		return "sql.NullTime", nil
+	}
+	return "", fmt.Errorf("column %s: type %s is not supported", c.Name, c.Data_type)
+}
+
+// table_funcs generates the struct of a table and its Insert function and,
+// when the table has a primary key, Get, Update and Delete.
+func (g *go_generator) table_funcs(t *table_def) error {
+	type_name := go_name(t.Name, true)
+	types := map[string]string{}
+	var b strings.Builder
+	fmt.Fprintf(&b, "// %s is a row of %s.%s.\ntype %s struct {\n", type_name, t.Owner, t.Name, type_name)
+	for _, c := range t.Columns {
+		gt, err := column_go_type(c)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Owner, t.Name, err)
+		}
+		g.use_type(gt)
+		types[c.Name] = gt
+		comment := c.Data_type
+		switch {
+		case c.Identity != "":
+			comment += " GENERATED " + c.Identity + " AS IDENTITY"
+		case c.Virtual:
+			comment += " VIRTUAL"
+		}
+		if c.Not_null {
+			comment += " NOT NULL"
+		}
+		fmt.Fprintf(&b, "%s %s // %s\n", go_name(c.Name, true), gt, comment)
+	}
+	b.WriteString("}\n")
+	g.structs[type_name] = b.String()
+
+	// Insert: generated columns come back through RETURNING INTO
+	var cols, values, binds, returning, into, temps, post []string
+	for _, c := range t.Columns {
+		field := "r." + go_name(c.Name, true)
+		bind := "b_" + strings.ToLower(c.Name)
+		if !c.generated() {
+			cols = append(cols, quote_identifier(c.Name))
+			values = append(values, ":"+bind)
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, %s)", bind, field))
+			continue
+		}
+		returning = append(returning, quote_identifier(c.Name))
+		into = append(into, ":"+bind)
+		switch types[c.Name] {
+		case "sql.NullString":
+			// RETURNING INTO needs a driver type; "" is NULL
+			tmp := go_name(c.Name, false) + "Out"
+			temps = append(temps, tmp)
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, sql.Out{Dest: &%s})", bind, tmp))
+			post = append(post, fmt.Sprintf("%s = sql.NullString{String: %s, Valid: %s != \"\"}", field, tmp, tmp))
+		case "sql.NullFloat64":
+			return fmt.Errorf("%s.%s: %s: nullable binary floats cannot be returned", t.Owner, t.Name, c.Name)
+		default:
+			binds = append(binds, fmt.Sprintf("sql.Named(%q, sql.Out{Dest: &%s})", bind, field))
+		}
+	}
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", t.qualified_name(), strings.Join(cols, ", "), strings.Join(values, ", "))
+	if len(returning) > 0 {
+		stmt += fmt.Sprintf(" RETURNING %s INTO %s", strings.Join(returning, ", "), strings.Join(into, ", "))
+	}
+	var f strings.Builder
+	fmt.Fprintf(&f, "// Insert%s inserts r into %s.%s.", type_name, t.Owner, t.Name)
+	if len(returning) > 0 {
+		f.WriteString(" Generated columns are set from RETURNING INTO.")
+	}
+	fmt.Fprintf(&f, "\nfunc Insert%s(ctx context.Context, db DB, r *%s) error {\n", type_name, type_name)
+	for _, tmp := range temps {
+		fmt.Fprintf(&f, "var %s string\n", tmp)
+	}
+	fmt.Fprintf(&f, "_, err := db.ExecContext(ctx, `%s`", stmt)
+	for _, bd := range binds {
+		f.WriteString(",\n" + bd)
+	}
+	f.WriteString(")\n")
+	if len(post) > 0 {
+		f.WriteString("if err != nil {\nreturn err\n}\n" + strings.Join(post, "\n") + "\nreturn nil\n}\n")
+	} else {
+		f.WriteString("return err\n}\n")
+	}
+	g.funcs = append(g.funcs, f.String())
+
+	if len(t.Primary_key) == 0 {
+		g.funcs = append(g.funcs, fmt.Sprintf("// %s.%s has no primary key: no Get%s, Update%s or Delete%s.\n",
+			t.Owner, t.Name, type_name, type_name, type_name))
+		return nil
+	}
+
+	// the primary key as parameters and as a WHERE clause
+	in_key := map[string]bool{}
+	var key_params, key_args, key_where, key_binds, key_fields []string
+	for _, k := range t.Primary_key {
+		in_key[k] = true
+		p := go_param_name(k)
+		bind := "b_" + strings.ToLower(k)
+		key_params = append(key_params, p+" "+types[k])
+		key_args = append(key_args, fmt.Sprintf("sql.Named(%q, %s)", bind, p))
+		key_where = append(key_where, fmt.Sprintf("%s = :%s", quote_identifier(k), bind))
+		key_binds = append(key_binds, fmt.Sprintf("sql.Named(%q, r.%s)", bind, go_name(k, true)))
+		key_fields = append(key_fields, k)
+	}
+	where := strings.Join(key_where, " AND ")
+	key_doc := strings.Join(key_fields, ", ")
+
+	// Get
+	var sel_cols, scans []string
+	for _, c := range t.Columns {
+		sel_cols = append(sel_cols, quote_identifier(c.Name))
+		scans = append(scans, "&r."+go_name(c.Name, true))
+	}
+	f.Reset()
+	fmt.Fprintf(&f, "// Get%s selects the %s.%s row with the given %s; the error is\n// sql.ErrNoRows when there is none.\n", type_name, t.Owner, t.Name, key_doc)
+	fmt.Fprintf(&f, "func Get%s(ctx context.Context, db DB, %s) (*%s, error) {\nvar r %s\n", type_name, strings.Join(key_params, ", "), type_name, type_name)
+	fmt.Fprintf(&f, "err := db.QueryRowContext(ctx, `SELECT %s FROM %s WHERE %s`,\n%s).Scan(%s)\n",
+		strings.Join(sel_cols, ", "), t.qualified_name(), where, strings.Join(key_args, ",\n"), strings.Join(scans, ", "))
+	f.WriteString("if err != nil {\nreturn nil, err\n}\nreturn &r, nil\n}\n")
+	g.funcs = append(g.funcs, f.String())
+
+	// Update: every column that is neither key nor generated
+	var sets, set_binds []string
+	for _, c := range t.Columns {
+		if in_key[c.Name] || c.generated() {
+			continue
+		}
+		bind := "b_" + strings.ToLower(c.Name)
+		sets = append(sets, fmt.Sprintf("%s = :%s", quote_identifier(c.Name), bind))
+		set_binds = append(set_binds, fmt.Sprintf("sql.Named(%q, r.%s)", bind, go_name(c.Name, true)))
+	}
+	if len(sets) > 0 {
+		f.Reset()
+		fmt.Fprintf(&f, "// Update%s writes r to the %s.%s row with r's %s and returns the\n// number of rows updated.\n", type_name, t.Owner, t.Name, key_doc)
+		fmt.Fprintf(&f, "func Update%s(ctx context.Context, db DB, r *%s) (int64, error) {\n", type_name, type_name)
+		fmt.Fprintf(&f, "res, err := db.ExecContext(ctx, `UPDATE %s SET %s WHERE %s`,\n%s)\n",
+			t.qualified_name(), strings.Join(sets, ", "), where, strings.Join(append(set_binds, key_binds...), ",\n"))
+		f.WriteString("if err != nil {\nreturn 0, err\n}\nreturn res.RowsAffected()\n}\n")
+		g.funcs = append(g.funcs, f.String())
+	}
+
+	// Delete
+	f.Reset()
+	fmt.Fprintf(&f, "// Delete%s deletes the %s.%s row with the given %s and returns the\n// number of rows deleted.\n", type_name, t.Owner, t.Name, key_doc)
+	fmt.Fprintf(&f, "func Delete%s(ctx context.Context, db DB, %s) (int64, error) {\n", type_name, strings.Join(key_params, ", "))
+	fmt.Fprintf(&f, "res, err := db.ExecContext(ctx, `DELETE FROM %s WHERE %s`,\n%s)\n", t.qualified_name(), where, strings.Join(key_args, ",\n"))
+	f.WriteString("if err != nil {\nreturn 0, err\n}\nreturn res.RowsAffected()\n}\n")
+	g.funcs = append(g.funcs, f.String())
+	return nil
+}
+
+func run_codegen_tables(args []string) error {
+	fs := flag.NewFlagSet("codegen tables", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema of the tables")
+	go_package := fs.String("package", "db", "Go package name of the generated file")
+	out := fs.String("out", "", "write the Go file here instead of stdout")
+	save := fs.String("save", "", "also write the table definitions as a YAML fixture (for codegen check)")
+	positional := parse_interspersed(fs, args)
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	names := positional
+	if len(names) == 0 {
+		if names, err = owner_tables(ctx, db, target); err != nil {
+			return err
+		}
+	}
+	fx := codegen_fixture{Go_package: *go_package}
+	for _, n := range names {
+		name, err := check_identifier("table", n)
+		if err != nil {
+			return err
+		}
+		t, err := read_table_def(ctx, db, target, name)
+		if err != nil {
+			return err
+		}
+		fx.Tables = append(fx.Tables, t)
+	}
+	return write_codegen(fx, *out, *save)
+}
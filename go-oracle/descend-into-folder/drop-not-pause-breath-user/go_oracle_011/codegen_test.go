@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func codegen_fixtures(t *testing.T) []string {
+	paths, err := filepath.Glob(filepath.Join(default_codegen_corpus, "*.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatalf("no .yaml fixtures in %s", default_codegen_corpus)
+	}
+	return paths
+}
+
+// TestCodegenGolden generates the Go file of every fixture in
+// testdata/codegen and compares it with <name>.golden.
+func TestCodegenGolden(t *testing.T) {
+	test_corpus(t, codegen_corpus)
+}
+
+// TestCodegenGoldenCompiles vets every golden file as a package of this
+// module, so the generated code is checked against the real godror API.
+// The package directory starts with "_" and is ignored by ./... patterns.
+func TestCodegenGoldenCompiles(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go vet")
+	}
+	go_tool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	for _, path := range codegen_fixtures(t) {
+		golden_path := strings.TrimSuffix(path, ".yaml") + ".golden"
+		t.Run(filepath.Base(golden_path), func(t *testing.T) {
+			src, err := os.ReadFile(golden_path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dir, err := os.MkdirTemp(".", "_codegen_")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			if err := os.WriteFile(filepath.Join(dir, "generated.go"), src, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			out, err := exec.Command(go_tool, "vet", "./"+filepath.ToSlash(dir)).CombinedOutput()
+			if err != nil {
+				t.Errorf("go vet %s: %v\n%s", golden_path, err, out)
+			}
+		})
+	}
+}
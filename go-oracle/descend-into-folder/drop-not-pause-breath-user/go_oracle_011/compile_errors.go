@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const error_context_lines = 2
+
+// compile_error is one row of ALL_ERRORS. When the object was deployed from
+// a local file, file/file_line/file_column point at the same spot in it.
+type compile_error struct {
+	Owner          string `json:"owner"`
+	Type           string `json:"type"`
+	Name           string `json:"name"`
+	Sequence       int    `json:"sequence"`
+	Line           int    `json:"line"`
+	Position       int    `json:"position"`
+	Attribute      string `json:"attribute"` // ERROR or WARNING
+	Message_number int    `json:"message_number"`
+	Code           string `json:"code"` // e.g. PLS-00201
+	Text           string `json:"text"`
+	File           string `json:"file,omitempty"`
+	File_line      int    `json:"file_line,omitempty"`
+	File_column    int    `json:"file_column,omitempty"`
+}
+
+// invalid_object_error is returned by deploy_ddl when the object compiled
+// INVALID; it carries the diagnostics for JSON/SARIF reports.
+type invalid_object_error struct {
+	obj    db_object
+	errors []compile_error
+}
+
+func (e *invalid_object_error) Error() string {
+	return fmt.Sprintf("%s %s is INVALID", strings.ToLower(e.obj.obj_type), e.obj.name)
+}
+
+// source_location maps ALL_SOURCE line numbers of an object to the local
+// file it was deployed from.
+type source_location struct {
+	file       string   // path as given on the command line
+	lines      []string // file content
+	first_line int      // file line (1-based) holding source line 1
+	first_col  int      // bytes before the source on that file line
+}
+
+var java_source_as_re = regexp.MustCompile(`(?i)\s(AS|IS)[ \t]*\r?\n?`)
+
+// statement_location returns the location of the object created by the
+// statement that starts at file line stmt_line. Oracle stores PL/SQL source
+// from the object type keyword on ("CREATE OR REPLACE" is dropped) and Java
+// source from the text after AS.
+func statement_location(file string, lines []string, stmt_line int, ddl string) *source_location {
+	m := ddl_head_re.FindStringSubmatchIndex(ddl)
+	if m == nil {
+		return nil
+	}
+	start := m[4] // the object type keyword
+	if strings.HasPrefix(strings.ToUpper(ddl[m[4]:m[5]]), "JAVA") {
+		if as := java_source_as_re.FindStringIndex(ddl[m[1]:]); as != nil {
+			start = m[1] + as[1]
+		}
+	}
+	before := ddl[:start]
+	loc := &source_location{file: file, lines: lines, first_line: stmt_line + strings.Count(before, "\n")}
+	loc.first_col = len(before) - (strings.LastIndex(before, "\n") + 1)
+	return loc
+}
+
+func (loc *source_location) file_position(line, pos int) (int, int) {
+	if line <= 0 {
+		return 0, 0
+	}
+	if line == 1 && pos > 0 {
+		pos += loc.first_col
+	}
+	return loc.first_line + line - 1, pos
+}
+
+var error_code_re = regexp.MustCompile(`^([A-Z]{2,3}-\d{5})`)
+
+// java_error_re matches javac-style messages ("Hello:12: error: ...") that
+// ALL_ERRORS reports with LINE 0 for Java sources.
+var java_error_re = regexp.MustCompile(`^[^:\s]+:(\d+):\s*(error|warning):`)
+
+// fetch_compile_errors reads ALL_ERRORS for one object, or for every object
+// of owner when obj_type and name are "".
+func fetch_compile_errors(ctx context.Context, db *sql.DB, owner, obj_type, name string) ([]compile_error, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT owner, type, name, sequence, line, position, text, attribute, message_number
+		FROM   all_errors
+		WHERE  owner = :1
+		  AND  (:2 IS NULL OR type = :3)
+		  AND  (:4 IS NULL OR name = :5)
+		ORDER BY type, name, sequence`, owner, obj_type, obj_type, name, name)
+	if err != nil {
+		return nil, fmt.Errorf("fetch compile errors failed: %w", err)
+	}
+	defer rows.Close()
+
+	var errs []compile_error
+	for rows.Next() {
+		var ce compile_error
+		var attribute sql.NullString
+		var message_number sql.NullInt64
+		if err := rows.Scan(&ce.Owner, &ce.Type, &ce.Name, &ce.Sequence, &ce.Line, &ce.Position,
+			&ce.Text, &attribute, &message_number); err != nil {
+			return nil, err
+		}
+		ce.Text = strings.TrimRight(ce.Text, "\r\n ")
+		ce.Attribute = attribute.String
+		ce.Message_number = int(message_number.Int64)
+		if m := error_code_re.FindStringSubmatch(ce.Text); m != nil {
+			ce.Code = m[1]
+		} else if ce.Message_number != 0 {
+			ce.Code = fmt.Sprintf("%05d", ce.Message_number)
+		}
+		if m := java_error_re.FindStringSubmatch(ce.Text); m != nil && ce.Line == 0 {
+			ce.Line, _ = strconv.Atoi(m[1])
+		}
+		errs = append(errs, ce)
+	}
+	return errs, rows.Err()
+}
+
+// object_source returns ALL_SOURCE of an object, indexed from line 1.
+func object_source(ctx context.Context, db *sql.DB, owner, obj_type, name string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT line, text
+		FROM   all_source
+		WHERE  owner = :1
+		  AND  type  = :2
+		  AND  name  = :3
+		ORDER BY line`, owner, obj_type, name)
+	if err != nil {
+		return nil, fmt.Errorf("fetch source failed: %w", err)
+	}
+	defer rows.Close()
+
+	lines := []string{""}
+	for rows.Next() {
+		var line int
+		var text sql.NullString
+		if err := rows.Scan(&line, &text); err != nil {
+			return nil, err
+		}
+		for len(lines) < line {
+			lines = append(lines, "")
+		}
+		lines = append(lines, strings.TrimRight(text.String, "\r\n"))
+	}
+	return lines, rows.Err()
+}
+
+// dump_compile_errors prints the errors and warnings of an object with the
+// offending source lines and a caret at the position, and returns them.
+// With loc the lines come from the local file and each error is mapped to
+// its file position; otherwise ALL_SOURCE is shown.
+func dump_compile_errors(ctx context.Context, db *sql.DB, owner, obj_type, name string, loc *source_location) ([]compile_error, error) {
+	errs, err := fetch_compile_errors(ctx, db, owner, obj_type, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(errs) == 0 {
+		fmt.Println("ℹ️ no entries in ALL_ERRORS")
+		return nil, nil
+	}
+
+	var lines []string // 1-based
+	if loc != nil {
+		lines = append([]string{""}, loc.lines...)
+		for i := range errs {
+			errs[i].File = filepath.ToSlash(loc.file)
+			errs[i].File_line, errs[i].File_column = loc.file_position(errs[i].Line, errs[i].Position)
+		}
+	} else if lines, err = object_source(ctx, db, owner, obj_type, name); err != nil {
+		return nil, err
+	}
+	for _, ce := range errs {
+		print_compile_error(ce, lines)
+	}
+	return errs, nil
+}
+
+func print_compile_error(ce compile_error, lines []string) {
+	icon := "❌"
+	if ce.Attribute == "WARNING" {
+		icon = "⚠️"
+	}
+	where := ""
+	line, pos := ce.Line, ce.Position
+	if ce.File != "" {
+		where = fmt.Sprintf(" (%s:%d)", ce.File, ce.File_line)
+		line, pos = ce.File_line, ce.File_column
+	}
+	fmt.Printf("%s [%d:%d] %s %s%s\n", icon, ce.Line, ce.Position, ce.Attribute, ce.Text, where)
+
+	if line <= 0 || line >= len(lines) {
+		return
+	}
+	for l := max(1, line-error_context_lines); l <= line; l++ {
+		fmt.Printf("   %5d | %s\n", l, lines[l])
+	}
+	if pos > 0 {
+		fmt.Printf("   %5s | %s^\n", "", caret_padding(lines[line], pos))
+	}
+}
+
+// caret_padding returns the blanks that put a caret under column pos,
+// keeping tabs so the caret lines up with the source.
+func caret_padding(line string, pos int) string {
+	var b strings.Builder
+	for i := 0; i < pos-1 && i < len(line); i++ {
+		if line[i] == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// ---- JSON / SARIF reports ----
+
+type report_flags struct {
+	path   string
+	format string
+}
+
+func add_report_flags(fs *flag.FlagSet) *report_flags {
+	rf := &report_flags{}
+	fs.StringVar(&rf.path, "report", "", "write compile errors to this file")
+	fs.StringVar(&rf.format, "report-format", "json", "format of -report: json or sarif")
+	return rf
+}
+
+// write writes the report when -report was given.
+func (rf *report_flags) write(errs []compile_error) error {
+	if rf.path == "" {
+		return nil
+	}
+	var doc any
+	switch rf.format {
+	case "json":
+		if errs == nil {
+			errs = []compile_error{}
+		}
+		doc = errs
+	case "sarif":
+		doc = sarif_report(errs)
+	default:
+		return fmt.Errorf("unknown -report-format %q (json or sarif)", rf.format)
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(rf.path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write report failed: %w", err)
+	}
+	fmt.Printf("📝 %s report with %d entries written to %s\n", rf.format, len(errs), rf.path)
+	return nil
+}
+
+// sarif_report builds a SARIF 2.1.0 log. Errors mapped to a local file point
+// at it; the others use a db:/OWNER/TYPE/NAME URI with the ALL_SOURCE line.
+func sarif_report(errs []compile_error) map[string]any {
+	rules := []map[string]any{}
+	seen := map[string]bool{}
+	results := []map[string]any{}
+	for _, ce := range errs {
+		rule := ce.Code
+		if rule == "" {
+			rule = "ORACLE"
+		}
+		if !seen[rule] {
+			seen[rule] = true
+			rules = append(rules, map[string]any{"id": rule})
+		}
+		level := "error"
+		if ce.Attribute == "WARNING" {
+			level = "warning"
+		}
+
+		uri := fmt.Sprintf("db:/%s/%s/%s", ce.Owner, strings.ReplaceAll(ce.Type, " ", "_"), ce.Name)
+		line, col := ce.Line, ce.Position
+		if ce.File != "" {
+			uri, line, col = ce.File, ce.File_line, ce.File_column
+		}
+		region := map[string]any{"startLine": max(line, 1)}
+		if col > 0 {
+			region["startColumn"] = col
+		}
+		results = append(results, map[string]any{
+			"ruleId":  rule,
+			"level":   level,
+			"message": map[string]any{"text": fmt.Sprintf("%s %s.%s: %s", ce.Type, ce.Owner, ce.Name, ce.Text)},
+			"locations": []map[string]any{{
+				"physicalLocation": map[string]any{
+					"artifactLocation": map[string]any{"uri": uri},
+					"region":           region,
+				},
+			}},
+		})
+	}
+	return map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs": []map[string]any{{
+			"tool":    map[string]any{"driver": map[string]any{"name": "go_oracle_011", "rules": rules}},
+			"results": results,
+		}},
+	}
+}
+
+// run_errors reports the current ALL_ERRORS of a schema with source context.
+func run_errors(args []string) error {
+	fs := flag.NewFlagSet("errors", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	rf := add_report_flags(fs)
+	owner := fs.String("owner", "", "schema to report")
+	object := fs.String("object", "", "only this object name")
+	fs.Parse(args)
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	name := ""
+	if *object != "" {
+		if name, err = check_identifier("object", *object); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	errs, err := fetch_compile_errors(ctx, db, target, "", name)
+	if err != nil {
+		return err
+	}
+	// group by object and show each with its ALL_SOURCE
+	var all []compile_error
+	for i := 0; i < len(errs); {
+		j := i
+		for j < len(errs) && errs[j].Type == errs[i].Type && errs[j].Name == errs[i].Name {
+			j++
+		}
+		fmt.Printf("🧩 %s %s.%s\n", errs[i].Type, errs[i].Owner, errs[i].Name)
+		lines, err := object_source(ctx, db, target, errs[i].Type, errs[i].Name)
+		if err != nil {
+			return err
+		}
+		for _, ce := range errs[i:j] {
+			print_compile_error(ce, lines)
+		}
+		all = append(all, errs[i:j]...)
+		i = j
+	}
+	if len(all) == 0 {
+		fmt.Printf("✅ no entries in ALL_ERRORS for %s\n", target)
+	}
+	return rf.write(all)
+}
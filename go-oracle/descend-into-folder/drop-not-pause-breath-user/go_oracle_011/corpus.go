@@ -0,0 +1,105 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// corpus_spec describes a directory of test cases: every file matching one
+// of globs is rendered and compared with the file of the same base name and
+// out_ext (grants.sql.tmpl -> grants.sql.expected). The "check" commands and
+// the tests of the package use the same specs.
+type corpus_spec struct {
+	what    string   // noun for messages, e.g. "script"
+	dir     string   // default directory
+	globs   []string // patterns of the case files
+	out_ext string   // ".expected" or ".golden"
+	render  func(path string) (string, error)
+}
+
+// corpus_case is the outcome of one case of check_corpus.
+type corpus_case struct {
+	path     string   // the case file
+	expected string   // the file holding its expected output
+	diff     []string // line_diff of the expected and the rendered output; nil if equal
+}
+
+// check_corpus renders every case of dir and compares the output, or
+// "error: ..." when render fails, with its expected file. With update the
+// expected files are rewritten instead and no case differs.
+func check_corpus(dir string, spec corpus_spec, update bool) ([]corpus_case, error) {
+	var paths []string
+	for _, glob := range spec.globs {
+		p, err := filepath.Glob(filepath.Join(dir, glob))
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, p...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no %s files in %s", strings.Join(spec.globs, " or "), dir)
+	}
+	sort.Strings(paths)
+
+	cases := make([]corpus_case, 0, len(paths))
+	for _, path := range paths {
+		c := corpus_case{path: path, expected: strings.TrimSuffix(path, filepath.Ext(path)) + spec.out_ext}
+		got, err := spec.render(path)
+		if err != nil {
+			got = "error: " + err.Error() + "\n"
+		}
+		if update {
+			if err := os.WriteFile(c.expected, []byte(got), 0o644); err != nil {
+				return nil, err
+			}
+			cases = append(cases, c)
+			continue
+		}
+		want, err := os.ReadFile(c.expected)
+		if err != nil {
+			return nil, err
+		}
+		if w := strings.ReplaceAll(string(want), "\r\n", "\n"); w != got {
+			c.diff = line_diff(w, got, 2)
+		}
+		cases = append(cases, c)
+	}
+	return cases, nil
+}
+
+// run_corpus_check is the "check" command of a corpus: an optional directory
+// argument (default spec.dir) and -update.
+func run_corpus_check(name string, spec corpus_spec, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	update := fs.Bool("update", false, "rewrite the "+spec.out_ext+" files from the current output")
+	positional := parse_interspersed(fs, args)
+	dir := spec.dir
+	if len(positional) > 0 {
+		dir = positional[0]
+	}
+
+	cases, err := check_corpus(dir, spec, *update)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, c := range cases {
+		switch {
+		case *update:
+			fmt.Printf("✏️ %s\n", c.expected)
+		case c.diff != nil:
+			fmt.Printf("❌ %s\n%s\n", c.path, strings.Join(c.diff, "\n"))
+			failed++
+		default:
+			fmt.Printf("✅ %s\n", c.path)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d %s(s) differ", failed, len(cases), spec.what)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden and .expected files instead of comparing")
+
+// test_corpus runs check_corpus on the default directory of spec with a
+// subtest per case; go test -update rewrites the expected files.
+func test_corpus(t *testing.T, spec corpus_spec) {
+	cases, err := check_corpus(spec.dir, spec, *update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		t.Run(filepath.Base(c.path), func(t *testing.T) {
+			if c.diff != nil {
+				t.Errorf("%s differs from %s (go test -update rewrites it)\n%s",
+					c.path, c.expected, strings.Join(c.diff, "\n"))
+			}
+		})
+	}
+}
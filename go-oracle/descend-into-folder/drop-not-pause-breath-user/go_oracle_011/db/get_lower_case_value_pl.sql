@@ -0,0 +1,5 @@
+CREATE OR REPLACE FUNCTION get_lower_case_value_pl(p_in VARCHAR2)
+  RETURN VARCHAR2 DETERMINISTIC
+AS LANGUAGE JAVA
+NAME 'get_lower_case_value.get_lower_case_value(java.lang.String) return java.lang.String';
+/
@@ -0,0 +1,7 @@
+CREATE OR REPLACE FUNCTION get_timestamp
+   RETURN TIMESTAMP WITH TIME ZONE
+AS
+BEGIN
+   RETURN CURRENT_TIMESTAMP;
+END get_timestamp;
+/
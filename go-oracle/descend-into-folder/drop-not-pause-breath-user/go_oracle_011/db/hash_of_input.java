@@ -0,0 +1,26 @@
+import java.io.*;
+import java.sql.*;
+import java.security.*;
+
+public class hash_of_input {
+    public static String hash_of_input(Clob clob) throws Exception {
+        if (clob == null) {
+            return null;
+        }
+        MessageDigest md = MessageDigest.getInstance("SHA-256");
+        Reader reader = clob.getCharacterStream();
+        char[] buffer = new char[8192];
+        int read;
+        while ((read = reader.read(buffer)) != -1) {
+            byte[] bytes = new String(buffer, 0, read).getBytes("UTF-8");
+            md.update(bytes);
+        }
+        reader.close();
+        byte[] digest = md.digest();
+        StringBuilder sb = new StringBuilder(digest.length * 2);
+        for (byte b : digest) {
+            sb.append(String.format("%02x", b & 0xff));
+        }
+        return sb.toString();
+    }
+}
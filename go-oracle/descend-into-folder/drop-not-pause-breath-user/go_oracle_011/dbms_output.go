@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"flag"
+	"fmt"
+	"io"
+)
+
+const dbms_output_fetch_size = 1000
+
+// get_lines_block fetches up to :1 buffered DBMS_OUTPUT lines and returns
+// them through a REF CURSOR, the same way grant_block returns its results.
+const get_lines_block = `
+DECLARE
+  l_lines sys.dbmsoutput_linesarray;
+  l_count INTEGER := :1;
+BEGIN
+  DBMS_OUTPUT.GET_LINES(l_lines, l_count);
+  OPEN :2 FOR SELECT column_value FROM TABLE(l_lines);
+END;`
+
+// sql_execer is a *sql.DB or a *sql.Tx; DBMS_OUTPUT is read on whichever
+// holds the session's connection.
+type sql_execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// enable_dbms_output turns on DBMS_OUTPUT buffering for the session with an
+// unlimited buffer (SET SERVEROUTPUT ON SIZE UNLIMITED).
+func enable_dbms_output(ctx context.Context, db sql_execer) error {
+	if _, err := db.ExecContext(ctx, "BEGIN DBMS_OUTPUT.ENABLE(NULL); END;"); err != nil {
+		return fmt.Errorf("enable DBMS_OUTPUT failed: %w", err)
+	}
+	return nil
+}
+
+func disable_dbms_output(ctx context.Context, db sql_execer) error {
+	if _, err := db.ExecContext(ctx, "BEGIN DBMS_OUTPUT.DISABLE; END;"); err != nil {
+		return fmt.Errorf("disable DBMS_OUTPUT failed: %w", err)
+	}
+	return nil
+}
+
+// fetch_dbms_output drains the DBMS_OUTPUT buffer of the session.
+func fetch_dbms_output(ctx context.Context, db sql_execer) ([]string, error) {
+	var lines []string
+	for {
+		var rset driver.Rows
+		if _, err := db.ExecContext(ctx, get_lines_block, dbms_output_fetch_size, sql.Out{Dest: &rset}); err != nil {
+			return nil, fmt.Errorf("read DBMS_OUTPUT failed: %w", err)
+		}
+		n := 0
+		vals := make([]driver.Value, len(rset.Columns()))
+		for {
+			if err := rset.Next(vals); err == io.EOF {
+				break
+			} else if err != nil {
+				rset.Close()
+				return nil, fmt.Errorf("read DBMS_OUTPUT failed: %w", err)
+			}
+			line, _ := vals[0].(string) // NULL for an empty PUT_LINE
+			lines = append(lines, line)
+			n++
+		}
+		rset.Close()
+		if n < dbms_output_fetch_size {
+			return lines, nil
+		}
+	}
+}
+
+// output_line is one DBMS_OUTPUT line and the statement that wrote it.
+type output_line struct {
+	Statement string `json:"statement"`
+	Text      string `json:"text"`
+}
+
+// output_capture collects DBMS_OUTPUT after every statement once enabled.
+// A nil capture collects nothing, so callers need not check the flag.
+type output_capture struct {
+	enabled bool
+	lines   []output_line
+}
+
+func add_output_flags(fs *flag.FlagSet) *output_capture {
+	c := &output_capture{}
+	fs.BoolVar(&c.enabled, "serveroutput", false, "enable DBMS_OUTPUT and print what each statement writes")
+	return c
+}
+
+func (c *output_capture) enable(ctx context.Context, db sql_execer) error {
+	if c == nil || !c.enabled {
+		return nil
+	}
+	return enable_dbms_output(ctx, db)
+}
+
+// collect drains the buffer and prints each line prefixed with statement,
+// e.g. "[db/app.sql line 12]".
+func (c *output_capture) collect(ctx context.Context, db sql_execer, statement string) error {
+	if c == nil || !c.enabled {
+		return nil
+	}
+	lines, err := fetch_dbms_output(ctx, db)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Printf("💬 [%s] %s\n", statement, line)
+		c.lines = append(c.lines, output_line{Statement: statement, Text: line})
+	}
+	return nil
+}
+
+// since returns the lines collected after mark (a previous len of lines).
+func (c *output_capture) since(mark int) []output_line {
+	if c == nil || mark >= len(c.lines) {
+		return nil
+	}
+	return c.lines[mark:]
+}
+
+func (c *output_capture) mark() int {
+	if c == nil {
+		return 0
+	}
+	return len(c.lines)
+}
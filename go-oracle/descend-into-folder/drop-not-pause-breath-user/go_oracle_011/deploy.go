@@ -0,0 +1,544 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const default_manifest_name = "deploy.yaml"
+
+var deploy_extensions = map[string]bool{
+	".sql":  true,
+	".pks":  true,
+	".pkb":  true,
+	".java": true,
+	".js":   true,
+}
+
+// deploy_manifest optionally fixes the deploy order. When units is present
+// exactly those files are deployed, in that order; otherwise every file of
+// the directory is deployed in dependency order.
+type deploy_manifest struct {
+	Units            []manifest_unit   `yaml:"units"`
+	Java_permissions []java_permission `yaml:"java_permissions"` // applied before the units
+	Mle              *mle_config       `yaml:"mle"`              // MLE environments, optional MLE
+}
+
+type manifest_unit struct {
+	File       string            `yaml:"file"`
+	Test_sql   string            `yaml:"test_sql"`   // optional smoke-test query, run after the file
+	Call_specs *call_spec_config `yaml:"call_specs"` // .java and .js: generate PL/SQL wrappers
+}
+
+// deploy_unit is one file of a deploy directory with the script items it
+// contains and the objects its CREATE statements define.
+type deploy_unit struct {
+	path       string // relative to the deploy directory, slash separated
+	text       string
+	items      []script_item
+	objects    []db_object // owner is "" unless the DDL names one
+	test_sql   string
+	call_specs *call_spec_config
+}
+
+func load_deploy_manifest(path string) (*deploy_manifest, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m deploy_manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// rendered returns the unit with test_sql and the strings of call_specs
+// rendered as templates with tc, e.g.
+//
+//	test_sql: SELECT {{quote .Username}}.get_timestamp FROM dual
+func (mu manifest_unit) rendered(tc *template_context) (manifest_unit, error) {
+	var err error
+	render := func(field, s string) string {
+		if err != nil || !strings.Contains(s, "{{") {
+			return s
+		}
+		var out string
+		if out, err = render_template(field, s, tc); err != nil {
+			err = fmt.Errorf("%s: %w", mu.File, err)
+		}
+		return out
+	}
+	mu.Test_sql = render("test_sql", mu.Test_sql)
+	if mu.Call_specs != nil {
+		cs := *mu.Call_specs
+		cs.Suffix = render("call_specs.suffix", cs.Suffix)
+		cs.Env = render("call_specs.env", cs.Env)
+		cs.Methods = slices.Clone(cs.Methods)
+		for i, m := range cs.Methods {
+			cs.Methods[i] = render("call_specs.methods", m)
+		}
+		types := make(map[string]string, len(cs.Types))
+		for k, v := range cs.Types {
+			types[k] = render("call_specs.types", v)
+		}
+		cs.Types = types
+		mu.Call_specs = &cs
+	}
+	return mu, err
+}
+
+// java_source_ddl wraps a .java file into a CREATE JAVA SOURCE statement
+// named after the file.
+func java_source_ddl(name, src string) string {
+	return fmt.Sprintf(`CREATE OR REPLACE AND COMPILE JAVA SOURCE NAMED "%s" AS
+%s`, name, src)
+}
+
+// mle_module_ddl wraps a .js file into an MLE JavaScript module named after
+// the file, which must therefore be a simple identifier.
+func mle_module_ddl(name, src string) (string, error) {
+	module, err := check_identifier("mle module", name)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`CREATE OR REPLACE MLE MODULE %s LANGUAGE JAVASCRIPT AS
+%s`, module, src), nil
+}
+
+func base_name(path string) string {
+	path = template_target(path)
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// unit_ext is the lower-case extension of a unit, ignoring .tmpl.
+func unit_ext(path string) string {
+	return strings.ToLower(filepath.Ext(template_target(path)))
+}
+
+// read_deploy_unit loads one file and detects the objects it defines.
+// .sql/.pks/.pkb files are SQL*Plus scripts; .java and .js files are the
+// source of a single object and are not parsed. A .tmpl suffix (e.g.
+// grants.sql.tmpl) renders the file with tc first.
+func read_deploy_unit(dir, rel string, defines map[string]string, tc *template_context) (*deploy_unit, error) {
+	text, err := read_source(filepath.Join(dir, filepath.FromSlash(rel)), tc)
+	if err != nil {
+		return nil, err
+	}
+	u := &deploy_unit{path: rel, text: text}
+
+	switch unit_ext(rel) {
+	// the generated CREATE line of .java and .js units is line 0, so that
+	// source line 1 maps to line 1 of the file
+	case ".java":
+		u.items = []script_item{{kind: item_plsql, text: java_source_ddl(base_name(rel), u.text), line: 0}}
+	case ".js":
+		ddl, err := mle_module_ddl(base_name(rel), u.text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+		u.items = []script_item{{kind: item_plsql, text: ddl, line: 0}}
+	default:
+		if u.items, err = parse_script(u.text, defines); err != nil {
+			return nil, fmt.Errorf("%s: %w", rel, err)
+		}
+	}
+	if len(statements(u.items)) == 0 {
+		return nil, fmt.Errorf("%s: no statements found", rel)
+	}
+	for _, it := range statements(u.items) {
+		if obj, err := parse_object_ddl(it.text); err == nil {
+			u.objects = append(u.objects, obj)
+		}
+	}
+	return u, nil
+}
+
+// load_deploy_units reads the deploy directory. With a manifest the listed
+// files are returned in manifest order; otherwise all files with a known
+// extension are returned in dependency order. The manifest is returned too
+// (nil if there is none).
+func load_deploy_units(dir, manifest_path string, defines map[string]string, tc *template_context) ([]*deploy_unit, *deploy_manifest, error) {
+	manifest, err := load_deploy_manifest(manifest_path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not load manifest: %w", err)
+	}
+
+	if manifest != nil && len(manifest.Units) > 0 {
+		fmt.Printf("📜 using manifest %s\n", manifest_path)
+		var units []*deploy_unit
+		for _, mu := range manifest.Units {
+			u, err := read_deploy_unit(dir, filepath.ToSlash(mu.File), defines, tc)
+			if err != nil {
+				return nil, nil, err
+			}
+			if mu, err = mu.rendered(tc); err != nil {
+				return nil, nil, err
+			}
+			u.test_sql = mu.Test_sql
+			if mu.Call_specs != nil {
+				if ext := unit_ext(u.path); ext != ".java" && ext != ".js" {
+					return nil, nil, fmt.Errorf("%s: call_specs is only supported for .java and .js files", u.path)
+				}
+				u.call_specs = mu.Call_specs
+			}
+			units = append(units, u)
+		}
+		return units, manifest, nil
+	}
+
+	var paths []string
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !deploy_extensions[unit_ext(path)] {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	sort.Strings(paths)
+
+	var units []*deploy_unit
+	for _, rel := range paths {
+		u, err := read_deploy_unit(dir, rel, defines, tc)
+		if err != nil {
+			return nil, nil, err
+		}
+		units = append(units, u)
+	}
+	return order_by_dependencies(units), manifest, nil
+}
+
+var word_re = regexp.MustCompile(`[A-Za-z][A-Za-z0-9_$#]*`)
+
+var java_class_re = regexp.MustCompile(`\bpublic\s+(?:final\s+|abstract\s+)*class\s+([A-Za-z_$][A-Za-z0-9_$]*)`)
+
+// order_by_dependencies sorts units so that every unit comes after the units
+// defining names it references. References are found by matching the words
+// of a unit (including string literals such as Java call-spec NAME clauses)
+// against the object names other units define. Bodies are never depended
+// upon, so a package body follows its spec but not the other way round.
+// Ties keep file order; a cycle is reported and broken in file order.
+func order_by_dependencies(units []*deploy_unit) []*deploy_unit {
+	definers := map[string][]int{}
+	for i, u := range units {
+		for _, obj := range u.objects {
+			if strings.HasSuffix(obj.obj_type, " BODY") {
+				continue
+			}
+			definers[strings.ToUpper(obj.name)] = append(definers[strings.ToUpper(obj.name)], i)
+		}
+		if unit_ext(u.path) == ".java" {
+			for _, m := range java_class_re.FindAllStringSubmatch(u.text, -1) {
+				definers[strings.ToUpper(m[1])] = append(definers[strings.ToUpper(m[1])], i)
+			}
+		}
+	}
+
+	deps := make([]map[int]bool, len(units))
+	for i, u := range units {
+		deps[i] = map[int]bool{}
+		for _, w := range word_re.FindAllString(u.text, -1) {
+			for _, j := range definers[strings.ToUpper(w)] {
+				if j != i {
+					deps[i][j] = true
+				}
+			}
+		}
+	}
+
+	done := make([]bool, len(units))
+	var ordered []*deploy_unit
+	for len(ordered) < len(units) {
+		next := -1
+		for i := range units {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for j := range deps[i] {
+				if !done[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// cycle: take the first remaining file
+			for i := range units {
+				if !done[i] {
+					next = i
+					break
+				}
+			}
+			fmt.Printf("⚠️ dependency cycle involving %s; deploying in file order\n", units[next].path)
+		}
+		done[next] = true
+		ordered = append(ordered, units[next])
+	}
+	return ordered
+}
+
+// deploy_result is the outcome of one unit in the -json results.
+type deploy_result struct {
+	File        string          `json:"file"`
+	Ok          bool            `json:"ok"`
+	Error       string          `json:"error,omitempty"`
+	Diagnostics []compile_error `json:"diagnostics,omitempty"`
+	Output      []output_line   `json:"output,omitempty"`
+}
+
+// deploy_units compiles every unit of dir into owner with deploy_ddl and
+// runs the optional smoke tests. Unless keep_going is set it stops at the
+// first failure. The results carry the compile errors of INVALID objects
+// and, with output enabled, the DBMS_OUTPUT of every statement. When there
+// is Java to deploy, JAVAVM is checked and the Java permissions of the
+// manifest are applied first; .js units need MLE and are skipped without it
+// if the manifest marks MLE optional.
+func deploy_units(ctx context.Context, db *sql.DB, owner, dir string, units []*deploy_unit, manifest *deploy_manifest, keep_going bool, output *output_capture) ([]deploy_result, error) {
+	var perms []java_permission
+	var mle *mle_config
+	if manifest != nil {
+		perms = manifest.Java_permissions
+		mle = manifest.Mle
+	}
+	units, mle, err := skip_mle_units(ctx, db, units, mle)
+	if err != nil {
+		return nil, err
+	}
+	if needs_java(units) || len(perms) > 0 {
+		if err := check_javavm(ctx, db); err != nil {
+			return nil, err
+		}
+		if err := apply_java_permissions(ctx, db, owner, perms); err != nil {
+			return nil, err
+		}
+	}
+	if err := output.enable(ctx, db); err != nil {
+		return nil, err
+	}
+
+	envs := new_mle_env_tracker(mle, units)
+	if err := envs.deployed(ctx, db, owner, nil); err != nil {
+		return nil, err
+	}
+
+	var results []deploy_result
+	fail_count := 0
+	for _, u := range units {
+		fmt.Printf("📄 %s\n", u.path)
+		mark := output.mark()
+		r := &script_runner{db: db, owner: owner, exit_on_error: true, output: output,
+			file: filepath.Join(dir, filepath.FromSlash(u.path)), lines: file_lines(u.text)}
+		err := r.run(ctx, u.path, u.items)
+		if err == nil {
+			err = envs.deployed(ctx, db, owner, u)
+		}
+		if err == nil && u.call_specs != nil {
+			if is_js_unit(u) {
+				err = deploy_mle_call_specs(ctx, db, owner, u.objects[0].name, u.text, u.call_specs)
+			} else {
+				err = deploy_call_specs(ctx, db, owner, u.text, u.call_specs)
+			}
+			if out_err := output.collect(ctx, db, u.path+" call specs"); out_err != nil && err == nil {
+				err = out_err
+			}
+		}
+		if err == nil && strings.TrimSpace(u.test_sql) != "" {
+			err = smoke_test(ctx, db, u.path, u.test_sql)
+			if out_err := output.collect(ctx, db, u.path+" test_sql"); out_err != nil && err == nil {
+				err = out_err
+			}
+		}
+		res := deploy_result{File: u.path, Ok: err == nil, Diagnostics: r.diagnostics, Output: output.since(mark)}
+		if err != nil {
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", u.path, err)
+			fail_count++
+			if !keep_going {
+				return results, fmt.Errorf("deploy stopped at %s: %w", u.path, err)
+			}
+		}
+	}
+	fmt.Printf("📊 deployed OK=%d, failed=%d\n", len(results)-fail_count, fail_count)
+	if fail_count > 0 {
+		return results, fmt.Errorf("%d file(s) failed", fail_count)
+	}
+	return results, nil
+}
+
+// needs_java reports whether any unit creates a Java object.
+func needs_java(units []*deploy_unit) bool {
+	for _, u := range units {
+		for _, obj := range u.objects {
+			if strings.HasPrefix(obj.obj_type, "JAVA ") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// write_json writes v as indented JSON to path.
+func write_json(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write %s failed: %w", path, err)
+	}
+	return nil
+}
+
+// smoke_test runs a single-value query and prints the result.
+func smoke_test(ctx context.Context, db *sql.DB, label, test_sql string) error {
+	var out any
+	if err := db.QueryRowContext(ctx, test_sql).Scan(&out); err != nil {
+		return fmt.Errorf("test failed: %w", err)
+	}
+	fmt.Printf("🧪 test: %s -> %v\n", label, out)
+	return nil
+}
+
+func run_deploy(args []string) error {
+	fs := flag.NewFlagSet("deploy", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema to compile the objects into")
+	manifest := fs.String("manifest", "", "manifest file (default: <dir>/"+default_manifest_name+" if present)")
+	dry_run := fs.Bool("dry-run", false, "only print the deploy order and detected objects")
+	keep_going := fs.Bool("keep-going", false, "continue after a failing file")
+	skip_mle := fs.Bool("skip-unsupported-mle", false, "skip .js units when the database does not support MLE (same as mle.optional)")
+	defines := add_define_flags(fs)
+	tf := add_template_flags(fs)
+	rf := add_report_flags(fs)
+	output := add_output_flags(fs)
+	json_path := fs.String("json", "", "write the result of every file (status, errors, DBMS_OUTPUT) as JSON to this file")
+	positional := parse_interspersed(fs, args)
+
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: deploy [flags] <dir>")
+	}
+	dir := positional[0]
+	if *manifest == "" {
+		*manifest = filepath.Join(dir, default_manifest_name)
+	}
+
+	// -dry-run works offline: templates see -owner (if any) and -container
+	var target string
+	if !*dry_run || *owner != "" {
+		var err error
+		if target, err = check_identifier("owner", *owner); err != nil {
+			return fmt.Errorf("-owner is required: %w", err)
+		}
+	}
+	tc, err := tf.context(cf, target)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	var db *sql.DB
+	if !*dry_run {
+		if db, err = cf.open(ctx); err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := tc.connect(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	units, m, err := load_deploy_units(dir, *manifest, defines, tc)
+	if err != nil {
+		return err
+	}
+	for i, u := range units {
+		var objs []string
+		for _, o := range u.objects {
+			objs = append(objs, o.String())
+		}
+		if u.call_specs != nil {
+			objs = append(objs, "+ call specs")
+		}
+		fmt.Printf("%3d. %-40s %s\n", i+1, u.path, strings.Join(objs, ", "))
+	}
+	if m != nil {
+		for _, p := range m.Java_permissions {
+			action := "grant"
+			if p.Revoke {
+				action = "revoke"
+			}
+			fmt.Printf("🔐 %s java permission %s\n", action, p)
+		}
+		if m.Mle != nil {
+			for _, e := range m.Mle.Environments {
+				fmt.Printf("🟨 %s\n", e.ddl(target))
+			}
+		}
+	}
+	if *skip_mle {
+		if m == nil {
+			m = &deploy_manifest{}
+		}
+		if m.Mle == nil {
+			m.Mle = &mle_config{}
+		}
+		m.Mle.Optional = true
+	}
+	if *dry_run {
+		return nil
+	}
+
+	results, err := deploy_units(ctx, db, target, dir, units, m, *keep_going, output)
+	var diagnostics []compile_error
+	for _, r := range results {
+		diagnostics = append(diagnostics, r.Diagnostics...)
+	}
+	if report_err := rf.write(diagnostics); report_err != nil && err == nil {
+		err = report_err
+	}
+	if *json_path != "" {
+		if results == nil {
+			results = []deploy_result{}
+		}
+		if json_err := write_json(*json_path, results); json_err != nil {
+			if err == nil {
+				err = json_err
+			}
+		} else {
+			fmt.Printf("📝 JSON results written to %s\n", *json_path)
+		}
+	}
+	return err
+}
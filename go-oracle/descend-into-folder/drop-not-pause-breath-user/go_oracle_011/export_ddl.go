@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ddl_layout maps ALL_OBJECTS types to their DBMS_METADATA type, the
+// directory of the export tree and the file suffix. Types not listed are
+// not exported.
+var ddl_layout = map[string]struct {
+	metadata_type string
+	dir           string
+	suffix        string
+}{
+	"TABLE":             {"TABLE", "tables", ".sql"},
+	"VIEW":              {"VIEW", "views", ".sql"},
+	"MATERIALIZED VIEW": {"MATERIALIZED_VIEW", "materialized_views", ".sql"},
+	"INDEX":             {"INDEX", "indexes", ".sql"},
+	"SEQUENCE":          {"SEQUENCE", "sequences", ".sql"},
+	"SYNONYM":           {"SYNONYM", "synonyms", ".sql"},
+	"FUNCTION":          {"FUNCTION", "functions", ".sql"},
+	"PROCEDURE":         {"PROCEDURE", "procedures", ".sql"},
+	"PACKAGE":           {"PACKAGE_SPEC", "packages", ".pks"},
+	"PACKAGE BODY":      {"PACKAGE_BODY", "packages", ".pkb"},
+	"TYPE":              {"TYPE_SPEC", "types", ".sql"},
+	"TYPE BODY":         {"TYPE_BODY", "types", "_body.sql"},
+	"TRIGGER":           {"TRIGGER", "triggers", ".sql"},
+	"JAVA SOURCE":       {"JAVA_SOURCE", "java", ".sql"},
+	"MLE MODULE":        {"MLE_MODULE", "mle", ".sql"},
+	"DATABASE LINK":     {"DB_LINK", "db_links", ".sql"},
+}
+
+// ddl_dependent_types are appended to the file of their base object when
+// -dependent is set.
+var ddl_dependent_types = []string{"COMMENT", "OBJECT_GRANT"}
+
+type export_options struct {
+	storage            bool
+	segment_attributes bool
+	emit_schema        bool
+	dependent          bool
+	no_ref_constraints bool // leave foreign keys out of table DDL
+}
+
+// set_metadata_transforms sets the session transform parameters of
+// DBMS_METADATA for every GET_DDL call that follows on the connection.
+func set_metadata_transforms(ctx context.Context, db *sql.DB, opts export_options) error {
+	sql_bool := map[bool]string{true: "TRUE", false: "FALSE"}
+	block := fmt.Sprintf(`BEGIN
+  DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'DEFAULT', TRUE);
+  DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'PRETTY', TRUE);
+  DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'SQLTERMINATOR', TRUE);
+  DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'STORAGE', %s);
+  DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'SEGMENT_ATTRIBUTES', %s);
+  DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'EMIT_SCHEMA', %s);
+  DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'REF_CONSTRAINTS', %s);
+END;`, sql_bool[opts.storage], sql_bool[opts.segment_attributes], sql_bool[opts.emit_schema],
+		sql_bool[!opts.no_ref_constraints])
+	if _, err := db.ExecContext(ctx, block); err != nil {
+		return fmt.Errorf("set DBMS_METADATA transforms failed: %w", err)
+	}
+	return nil
+}
+
+// exportable_objects lists the objects of owner that have a layout entry,
+// leaving out generated, secondary and recycle bin objects.
+func exportable_objects(ctx context.Context, db *sql.DB, owner string) ([]db_object, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT object_type, object_name
+		FROM   all_objects
+		WHERE  owner = :1
+		  AND  generated = 'N'
+		  AND  secondary = 'N'
+		  AND  object_name NOT LIKE 'BIN$%'
+		ORDER BY object_type, object_name`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query all_objects failed: %w", err)
+	}
+	defer rows.Close()
+
+	var objs []db_object
+	for rows.Next() {
+		o := db_object{owner: owner}
+		if err := rows.Scan(&o.obj_type, &o.name); err != nil {
+			return nil, err
+		}
+		if _, ok := ddl_layout[o.obj_type]; ok {
+			objs = append(objs, o)
+		}
+	}
+	return objs, rows.Err()
+}
+
+var unsafe_file_chars_re = regexp.MustCompile(`[^a-z0-9_.$#-]+`)
+
+// ddl_file_path is the path of an object relative to the export directory,
+// e.g. packages/billing.pkb.
+func ddl_file_path(o db_object) string {
+	l := ddl_layout[o.obj_type]
+	name := unsafe_file_chars_re.ReplaceAllString(strings.ToLower(o.name), "_")
+	return filepath.Join(l.dir, name+l.suffix)
+}
+
+// is_no_metadata_found is ORA-31608/ORA-31603: the object (or dependent
+// DDL such as comments) does not exist.
+func is_no_metadata_found(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "ORA-31608") || strings.Contains(err.Error(), "ORA-31603"))
+}
+
+// normalize_ddl trims the blank lines and trailing spaces DBMS_METADATA
+// emits so that exports of an unchanged schema are byte-identical.
+func normalize_ddl(ddl string) string {
+	lines := strings.Split(strings.ReplaceAll(ddl, "\r\n", "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n") + "\n"
+}
+
+// object_ddl fetches the DDL of an object and, with dependent set, the
+// comments and grants on it.
+func object_ddl(ctx context.Context, db *sql.DB, o db_object, dependent bool) (string, error) {
+	l := ddl_layout[o.obj_type]
+	var ddl string
+	err := db.QueryRowContext(ctx, "SELECT DBMS_METADATA.GET_DDL(:1, :2, :3) FROM dual",
+		l.metadata_type, o.name, o.owner).Scan(&ddl)
+	if err != nil {
+		return "", err
+	}
+	parts := []string{normalize_ddl(ddl)}
+	if dependent && !strings.HasSuffix(o.obj_type, " BODY") {
+		for _, dt := range ddl_dependent_types {
+			var dep string
+			err := db.QueryRowContext(ctx, "SELECT DBMS_METADATA.GET_DEPENDENT_DDL(:1, :2, :3) FROM dual",
+				dt, o.name, o.owner).Scan(&dep)
+			if is_no_metadata_found(err) {
+				continue
+			}
+			if err != nil {
+				return "", fmt.Errorf("%s: %w", strings.ToLower(dt), err)
+			}
+			parts = append(parts, normalize_ddl(dep))
+		}
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// remove_stale_exports deletes files of the layout directories under dir
+// that belong to no object of the schema, so the tree mirrors the schema.
+func remove_stale_exports(dir string, kept map[string]bool) (int, error) {
+	dirs := map[string]bool{}
+	for _, l := range ddl_layout {
+		dirs[l.dir] = true
+	}
+	removed := 0
+	for d := range dirs {
+		root := filepath.Join(dir, d)
+		err := filepath.WalkDir(root, func(path string, e fs.DirEntry, err error) error {
+			if os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			if err != nil || e.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil || kept[rel] {
+				return err
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			fmt.Printf("🗑️ removed %s\n", rel)
+			removed++
+			return nil
+		})
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+func run_export_ddl(args []string) error {
+	fs := flag.NewFlagSet("export-ddl", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema to export")
+	out := fs.String("out", "", "output directory (default: ddl/<owner>)")
+	types := fs.String("types", "", "comma-separated ALL_OBJECTS types to export (default: all supported)")
+	storage := fs.Bool("storage", false, "keep STORAGE clauses")
+	segment := fs.Bool("segment-attributes", false, "keep segment attributes (tablespace, logging, physical attributes)")
+	emit_schema := fs.Bool("emit-schema", false, "qualify object names with the schema")
+	dependent := fs.Bool("dependent", true, "append comments and object grants to the object's file")
+	keep_stale := fs.Bool("keep-stale", false, "do not remove files of objects that no longer exist")
+	fs.Parse(args)
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	if *out == "" {
+		*out = filepath.Join("ddl", strings.ToLower(target))
+	}
+	wanted := map[string]bool{}
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.ToUpper(strings.TrimSpace(t)); t == "" {
+			continue
+		}
+		if _, ok := ddl_layout[t]; !ok {
+			return fmt.Errorf("type %q is not exported", t)
+		}
+		wanted[t] = true
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	opts := export_options{storage: *storage, segment_attributes: *segment, emit_schema: *emit_schema, dependent: *dependent}
+	if err := set_metadata_transforms(ctx, db, opts); err != nil {
+		return err
+	}
+	objs, err := exportable_objects(ctx, db, target)
+	if err != nil {
+		return err
+	}
+
+	written := map[string]bool{}
+	kept := map[string]bool{} // every listed object, exported or not
+	counts := map[string]int{}
+	failed := 0
+	for _, o := range objs {
+		if len(wanted) > 0 && !wanted[o.obj_type] {
+			continue
+		}
+		// a failed fetch keeps the file of the last export: the object
+		// still exists, so the file is not stale
+		rel := ddl_file_path(o)
+		kept[rel] = true
+		ddl, err := object_ddl(ctx, db, o, opts.dependent)
+		if err != nil {
+			fmt.Printf("❌ %s: %v\n", o, err)
+			failed++
+			continue
+		}
+		if written[rel] {
+			return fmt.Errorf("%s: %s is written twice (names differing only in case?)", o, rel)
+		}
+		path := filepath.Join(*out, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(ddl), 0o644); err != nil {
+			return err
+		}
+		written[rel] = true
+		counts[ddl_layout[o.obj_type].dir]++
+	}
+
+	removed := 0
+	if !*keep_stale && len(wanted) == 0 {
+		if removed, err = remove_stale_exports(*out, kept); err != nil {
+			return err
+		}
+	}
+
+	var dirs []string
+	for d := range counts {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+	for _, d := range dirs {
+		fmt.Printf("📦 %-20s %d file(s)\n", d+"/", counts[d])
+	}
+	fmt.Printf("📊 exported %d object(s) of %s to %s, failed=%d, removed=%d\n", len(written), target, *out, failed, removed)
+	if failed > 0 {
+		return fmt.Errorf("%d object(s) could not be exported", failed)
+	}
+	return nil
+}
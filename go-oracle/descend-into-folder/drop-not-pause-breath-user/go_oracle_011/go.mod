@@ -0,0 +1,21 @@
+module go_oracle_011
+
+go 1.24.4
+
+require (
+	github.com/PeterCullenBurbery/go_functions_002/v5 v5.7.0
+	github.com/goccy/go-yaml v1.18.0
+	github.com/godror/godror v0.49.1
+)
+
+require (
+	github.com/VictoriaMetrics/easyproto v0.1.4 // indirect
+	github.com/go-logfmt/logfmt v0.6.0 // indirect
+	github.com/go-ole/go-ole v1.3.0 // indirect
+	github.com/godror/knownpb v0.3.0 // indirect
+	github.com/yeka/zip v0.0.0-20231116150916-03d6312748a9 // indirect
+	golang.org/x/crypto v0.39.0 // indirect
+	golang.org/x/exp v0.0.0-20250506013437-ce4c2cf36ca6 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	google.golang.org/protobuf v1.36.6 // indirect
+)
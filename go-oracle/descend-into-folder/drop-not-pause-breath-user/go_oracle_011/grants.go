@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const default_grant_batch_size = 500
+
+// grant_block runs every statement through EXECUTE IMMEDIATE inside its own
+// exception handler. Failures are collected in a SQL collection as
+// "<statement number>:<SQLERRM>" and returned through a REF CURSOR, so a
+// whole batch costs one round trip. The rows carry their statement number
+// because a collection cursor without ORDER BY has no guaranteed order.
+const grant_block = `
+DECLARE
+  l_res sys.odcivarchar2list := sys.odcivarchar2list();
+  l_n   PLS_INTEGER := 0;
+  PROCEDURE run(p_stmt VARCHAR2) IS
+  BEGIN
+    l_n := l_n + 1;
+    EXECUTE IMMEDIATE p_stmt;
+  EXCEPTION
+    WHEN OTHERS THEN
+      l_res.EXTEND;
+      l_res(l_res.COUNT) := SUBSTR(l_n || ':' || SQLERRM, 1, 4000);
+  END;
+BEGIN
+%s
+  OPEN :1 FOR SELECT column_value FROM TABLE(l_res);
+END;`
+
+// grant_error is the per-statement error reported by the server; it prints
+// as the ORA message, like the error of a single ExecContext.
+type grant_error string
+
+func (e grant_error) Error() string { return string(e) }
+
+// exec_batched runs stmts in anonymous blocks of at most batch_size
+// statements and returns one error (nil for success) per statement.
+func exec_batched(ctx context.Context, db *sql.DB, stmts []string, batch_size int) ([]error, error) {
+	if batch_size <= 0 {
+		batch_size = default_grant_batch_size
+	}
+	results := make([]error, 0, len(stmts))
+	for start := 0; start < len(stmts); start += batch_size {
+		end := min(start+batch_size, len(stmts))
+		batch, err := exec_block(ctx, db, stmts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, batch...)
+	}
+	return results, nil
+}
+
+func exec_block(ctx context.Context, db *sql.DB, stmts []string) ([]error, error) {
+	var body strings.Builder
+	for _, stmt := range stmts {
+		fmt.Fprintf(&body, "  run(%s);\n", quote_literal(stmt))
+	}
+
+	var rset driver.Rows
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(grant_block, body.String()), sql.Out{Dest: &rset}); err != nil {
+		return nil, fmt.Errorf("grant block failed: %w", err)
+	}
+	defer rset.Close()
+
+	results := make([]error, len(stmts))
+	vals := make([]driver.Value, len(rset.Columns()))
+	for {
+		if err := rset.Next(vals); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("read grant results failed: %w", err)
+		}
+		row, _ := vals[0].(string)
+		num, msg, _ := strings.Cut(row, ":")
+		i, err := strconv.Atoi(num)
+		if err != nil || i < 1 || i > len(stmts) || results[i-1] != nil {
+			return nil, fmt.Errorf("grant block returned unexpected result %q", row)
+		}
+		results[i-1] = grant_error(msg)
+	}
+	return results, nil
+}
+
+// grant_item is one role or system privilege to grant; kind is the label
+// used in the report ("role", "sys priv").
+type grant_item struct {
+	kind string
+	name string
+}
+
+func grant_list(kind string, names []string) []grant_item {
+	var items []grant_item
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			items = append(items, grant_item{kind, name})
+		}
+	}
+	return items
+}
+
+// grant_items grants every item to username in as few blocks as batch_size
+// allows and returns one result per item.
+func grant_items(ctx context.Context, db *sql.DB, username string, items []grant_item, batch_size int) ([]error, error) {
+	stmts := make([]string, len(items))
+	for i, item := range items {
+		stmts[i] = fmt.Sprintf("GRANT %s TO %s CONTAINER=CURRENT", item.name, username)
+	}
+	return exec_batched(ctx, db, stmts, batch_size)
+}
+
+// report_grants prints the per-item lines for the items of one kind and
+// returns the OK and failed counts.
+func report_grants(username, kind string, items []grant_item, results []error) (ok_count, fail_count int) {
+	for i, item := range items {
+		if item.kind != kind {
+			continue
+		}
+		if results[i] != nil {
+			fmt.Printf("❌ grant %s %-35s -> %s (error: %v)\n", kind, item.name, username, results[i])
+			fail_count++
+			continue
+		}
+		fmt.Printf("✅ grant %s %-35s -> %s\n", kind, item.name, username)
+		ok_count++
+	}
+	return ok_count, fail_count
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/godror/godror"
+)
+
+// hash_chunk is the char[] buffer size of hash_of_input.java; sizes around
+// its multiples are where chunked UTF-8 encoding can go wrong.
+const hash_chunk = 8192
+
+// hash_mixes generate the Unicode mixes of the verification; each returns
+// the rune at a UTF-16 offset. "boundary" puts a supplementary character (a
+// UTF-16 surrogate pair) across every chunk boundary.
+var hash_mixes = map[string]func(r *rand.Rand, units int) rune{
+	"ascii": func(r *rand.Rand, _ int) rune { return rune(0x20 + r.IntN(0x5f)) },
+	"latin": func(r *rand.Rand, _ int) rune {
+		if r.IntN(2) == 0 {
+			return rune(0x20 + r.IntN(0x5f))
+		}
+		return rune(0xa0 + r.IntN(0xe0))
+	},
+	"cjk":   func(r *rand.Rand, _ int) rune { return rune(0x4e00 + r.IntN(0x5200)) },
+	"emoji": func(r *rand.Rand, _ int) rune { return rune(0x1f300 + r.IntN(0x800)) },
+	"mixed": func(r *rand.Rand, _ int) rune {
+		switch r.IntN(6) {
+		case 0:
+			return rune(0x20 + r.IntN(0x5f))
+		case 1:
+			return rune(0xa0 + r.IntN(0xe0))
+		case 2:
+			return rune(0x300 + r.IntN(0x70)) // combining marks
+		case 3:
+			return rune(0x4e00 + r.IntN(0x5200))
+		case 4:
+			return rune(0x1f300 + r.IntN(0x800))
+		}
+		return '\n'
+	},
+	"boundary": func(r *rand.Rand, units int) rune {
+		if (units+1)%hash_chunk == 0 {
+			return rune(0x1f600 + r.IntN(0x50))
+		}
+		return 'a'
+	},
+}
+
+var default_hash_sizes = []int{0, 1, 2, 100, hash_chunk - 1, hash_chunk, hash_chunk + 1,
+	2*hash_chunk - 1, 2 * hash_chunk, 2*hash_chunk + 1, 100_000}
+
+// hash_input is one text to verify.
+type hash_input struct {
+	label string
+	text  string
+}
+
+// generate_hash_input returns a text of the mix with exactly units UTF-16
+// code units, the unit hash_of_input reads in.
+func generate_hash_input(r *rand.Rand, mix string, units int) string {
+	next := hash_mixes[mix]
+	var b strings.Builder
+	n := 0
+	for n < units {
+		c := next(r, n)
+		if utf16.RuneLen(c) > units-n {
+			c = 'a'
+		}
+		b.WriteRune(c)
+		n += utf16.RuneLen(c)
+	}
+	return b.String()
+}
+
+func utf16_len(s string) int {
+	n := 0
+	for _, c := range s {
+		n += utf16.RuneLen(c)
+	}
+	return n
+}
+
+func go_sha256(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// hash_verifier calls the hash function (a quoted, owner-qualified name)
+// with a CLOB streamed from Go.
+type hash_verifier struct {
+	db    *sql.DB
+	block string
+	calls int
+}
+
+func new_hash_verifier(db *sql.DB, name string) *hash_verifier {
+	return &hash_verifier{db: db, block: `DECLARE
+  c CLOB := :1;
+BEGIN
+  :2 := ` + name + `(c);
+  :3 := DBMS_LOB.GETLENGTH(c);
+END;`}
+}
+
+// hash_result is the database side of one input: the digest and the CLOB
+// length in characters as stored.
+type hash_result struct {
+	digest string
+	length int64
+}
+
+func (v *hash_verifier) hash(ctx context.Context, text string) (hash_result, error) {
+	v.calls++
+	var digest sql.NullString
+	var length sql.NullInt64
+	_, err := v.db.ExecContext(ctx, v.block,
+		godror.Lob{Reader: strings.NewReader(text), IsClob: true},
+		sql.Out{Dest: &digest}, sql.Out{Dest: &length})
+	if err != nil {
+		return hash_result{}, err
+	}
+	return hash_result{digest: digest.String, length: length.Int64}, nil
+}
+
+// mismatch reports whether the database disagrees with Go for text. An
+// empty CLOB reaches the function as NULL, so a NULL digest is expected.
+func (v *hash_verifier) mismatch(ctx context.Context, text string) (bool, hash_result, error) {
+	res, err := v.hash(ctx, text)
+	if err != nil {
+		return false, res, err
+	}
+	if text == "" {
+		return res.digest != "" && res.digest != go_sha256(""), res, nil
+	}
+	return res.digest != go_sha256(text), res, nil
+}
+
+// shrink_mismatch reduces a mismatching text by removing rune ranges
+// (delta debugging) while the mismatch persists, within budget calls.
+func (v *hash_verifier) shrink_mismatch(ctx context.Context, text string, budget int) (string, error) {
+	runes := []rune(text)
+	n := 2
+	for len(runes) >= 2 && budget > 0 {
+		size := (len(runes) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(runes) && budget > 0; start += size {
+			end := min(start+size, len(runes))
+			candidate := append(append([]rune{}, runes[:start]...), runes[end:]...)
+			budget--
+			bad, _, err := v.mismatch(ctx, string(candidate))
+			if err != nil {
+				return "", err
+			}
+			if bad {
+				runes = candidate
+				n = max(n-1, 2)
+				reduced = true
+				break
+			}
+		}
+		if !reduced {
+			if n >= len(runes) {
+				break
+			}
+			n = min(2*n, len(runes))
+		}
+	}
+	return string(runes), nil
+}
+
+// describe_runs summarizes a text as runs of equal runes,
+// e.g. "'a' x 8191 + U+1F600".
+func describe_runs(text string) string {
+	var parts []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && runes[j] == runes[i] {
+			j++
+		}
+		part := fmt.Sprintf("U+%04X", runes[i])
+		if runes[i] >= 0x20 && runes[i] < 0x7f {
+			part = strconv.QuoteRune(runes[i])
+		}
+		if j-i > 1 {
+			part += fmt.Sprintf(" x %d", j-i)
+		}
+		parts = append(parts, part)
+		i = j
+	}
+	if len(parts) > 12 {
+		parts = append(parts[:12], fmt.Sprintf("... (%d runs)", len(parts)))
+	}
+	return strings.Join(parts, " + ")
+}
+
+// unistr_literal returns UNISTR('...') for a run of one rune, with
+// surrogate pairs for supplementary characters.
+func unistr_literal(c rune) string {
+	if c >= 0x20 && c < 0x7f && c != '\'' && c != '\\' {
+		return quote_literal(string(c))
+	}
+	var b strings.Builder
+	for _, u := range utf16.Encode([]rune{c}) {
+		fmt.Fprintf(&b, `\%04X`, u)
+	}
+	return "UNISTR('" + b.String() + "')"
+}
+
+// reproducer_block returns a PL/SQL block that rebuilds text in a
+// temporary CLOB and prints the database digest next to the expected one.
+func reproducer_block(function, text string) string {
+	var b strings.Builder
+	b.WriteString("SET SERVEROUTPUT ON\nDECLARE\n  c CLOB;\nBEGIN\n  DBMS_LOB.CREATETEMPORARY(c, TRUE);\n")
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i
+		for j < len(runes) && runes[j] == runes[i] {
+			j++
+		}
+		lit := unistr_literal(runes[i])
+		units := utf16.RuneLen(runes[i])
+		if j-i == 1 {
+			fmt.Fprintf(&b, "  DBMS_LOB.WRITEAPPEND(c, %d, %s);\n", units, lit)
+		} else {
+			fmt.Fprintf(&b, "  FOR i IN 1 .. %d LOOP DBMS_LOB.WRITEAPPEND(c, %d, %s); END LOOP;\n", j-i, units, lit)
+		}
+		i = j
+	}
+	fmt.Fprintf(&b, "  DBMS_OUTPUT.PUT_LINE('got      ' || %s(c));\n", function)
+	fmt.Fprintf(&b, "  DBMS_OUTPUT.PUT_LINE('expected %s');\nEND;\n/\n", go_sha256(text))
+	return b.String()
+}
+
+// read_hash_files loads UTF-8 text files (files or directories) as inputs.
+func read_hash_files(paths []string) ([]hash_input, error) {
+	var inputs []hash_input
+	add := func(path string) error {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(data) {
+			fmt.Printf("⚠️ skipping %s: not valid UTF-8\n", path)
+			return nil
+		}
+		inputs = append(inputs, hash_input{label: path, text: string(data)})
+		return nil
+	}
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if err := add(p); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			return add(path)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return inputs, nil
+}
+
+func parse_sizes(s string) ([]int, error) {
+	if s == "" {
+		return default_hash_sizes, nil
+	}
+	var sizes []int
+	for _, f := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(f))
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid size %q", f)
+		}
+		sizes = append(sizes, n)
+	}
+	return sizes, nil
+}
+
+func run_verify_hash(args []string) error {
+	fs := flag.NewFlagSet("verify-hash", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema holding the function")
+	function := fs.String("function", "hash_of_input", "function taking a CLOB and returning the SHA-256 hex digest")
+	sizes_flag := fs.String("sizes", "", "comma-separated input sizes in UTF-16 code units (default: around multiples of 8192)")
+	mixes_flag := fs.String("mixes", "", "comma-separated mixes (default: all of ascii, latin, cjk, emoji, mixed, boundary)")
+	seed := fs.Uint64("seed", 1, "seed of the generated inputs")
+	budget := fs.Int("shrink-budget", 200, "database calls spent shrinking each mismatch to a minimal reproducer")
+	repro_dir := fs.String("repro-dir", "", "write each minimal reproducer (text and PL/SQL) to this directory")
+	positional := parse_interspersed(fs, args)
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	sizes, err := parse_sizes(*sizes_flag)
+	if err != nil {
+		return err
+	}
+	var mixes []string
+	for name := range hash_mixes {
+		mixes = append(mixes, name)
+	}
+	sort.Strings(mixes)
+	if *mixes_flag != "" {
+		mixes = strings.Split(*mixes_flag, ",")
+		for _, m := range mixes {
+			if hash_mixes[m] == nil {
+				return fmt.Errorf("unknown mix %q", m)
+			}
+		}
+	}
+
+	r := rand.New(rand.NewPCG(*seed, *seed))
+	var inputs []hash_input
+	for _, mix := range mixes {
+		for _, size := range sizes {
+			inputs = append(inputs, hash_input{
+				label: fmt.Sprintf("%s/%d", mix, size),
+				text:  generate_hash_input(r, mix, size),
+			})
+		}
+	}
+	files, err := read_hash_files(positional)
+	if err != nil {
+		return err
+	}
+	inputs = append(inputs, files...)
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var charset string
+	if err := db.QueryRowContext(ctx, `SELECT value FROM nls_database_parameters
+		WHERE parameter = 'NLS_CHARACTERSET'`).Scan(&charset); err != nil {
+		return fmt.Errorf("query NLS_CHARACTERSET failed: %w", err)
+	}
+	if charset != "AL32UTF8" && charset != "UTF8" {
+		fmt.Printf("⚠️ database character set is %s; non-ASCII inputs are converted before they reach the function\n", charset)
+	}
+
+	function_name := quote_identifier(target) + "." + quote_identifier(normalize_identifier(*function))
+	v := new_hash_verifier(db, function_name)
+	fmt.Printf("%-40s %9s %9s %s\n", "INPUT", "UTF16", "BYTES", "RESULT")
+	var failed []hash_input
+	stored_diff := 0
+	for _, in := range inputs {
+		bad, res, err := v.mismatch(ctx, in.text)
+		if err != nil {
+			return fmt.Errorf("%s: %w", in.label, err)
+		}
+		units := utf16_len(in.text)
+		mark := "✅"
+		switch {
+		case res.length != int64(units):
+			mark = fmt.Sprintf("⚠️ stored %d chars (character set conversion)", res.length)
+			stored_diff++
+		case bad:
+			mark = "❌ got " + res.digest
+			failed = append(failed, in)
+		}
+		fmt.Printf("%-40s %9d %9d %s\n", in.label, units, len(in.text), mark)
+	}
+	fmt.Printf("📊 inputs=%d, mismatches=%d, converted=%d\n", len(inputs), len(failed), stored_diff)
+
+	for i, in := range failed {
+		start := v.calls
+		small, err := v.shrink_mismatch(ctx, in.text, *budget)
+		if err != nil {
+			return fmt.Errorf("shrink %s: %w", in.label, err)
+		}
+		res, err := v.hash(ctx, small)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\n🔎 minimal reproducer for %s (%d calls): %d chars, %d UTF-16 units, %d UTF-8 bytes\n",
+			in.label, v.calls-start, utf8.RuneCountInString(small), utf16_len(small), len(small))
+		fmt.Printf("   input:    %s\n   expected: %s\n   got:      %s\n", describe_runs(small), go_sha256(small), res.digest)
+		block := reproducer_block(function_name, small)
+		fmt.Print(block)
+		if *repro_dir != "" {
+			if err := os.MkdirAll(*repro_dir, 0o755); err != nil {
+				return err
+			}
+			base := filepath.Join(*repro_dir, fmt.Sprintf("repro_%02d", i+1))
+			if err := os.WriteFile(base+".txt", []byte(small), 0o644); err != nil {
+				return err
+			}
+			if err := os.WriteFile(base+".sql", []byte(block), 0o644); err != nil {
+				return err
+			}
+			fmt.Printf("📝 written %s.txt and %s.sql\n", base, base)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d input(s) hash differently in the database", len(failed))
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func run_java(args []string) error {
+	return run_group("java", []command{
+		{"check", "report the JAVAVM component from DBA_REGISTRY and fail unless it is VALID", run_java_check},
+		{"policy", "list DBA_JAVA_POLICY for -owner", run_java_policy},
+		{"load", "upload .class files, JARs and resources as JAVA CLASS/RESOURCE, resolve them and list the owner's Java policy", run_java_load},
+		{"callspecs", "generate (and with -owner compile and smoke-test) PL/SQL call specs for a Java source", run_java_callspecs},
+		{"list", "list JAVA SOURCE/CLASS/RESOURCE of -owner with status, long name and owning source", run_java_list},
+		{"drop", "drop Java sources (with their compiled classes), classes or resources of -owner", run_java_drop},
+		{"resolve", "compile INVALID Java sources and resolve INVALID classes, reporting what is still invalid", run_java_resolve},
+	}, args)
+}
+
+func run_java_callspecs(args []string) error {
+	fs := flag.NewFlagSet("java callspecs", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema holding the compiled class; without it the call specs are only printed")
+	spec := fs.String("spec", "", "YAML with suffix, methods, types, from_db, no_test")
+	suffix := fs.String("suffix", "", "appended to the method names (overrides the config)")
+	positional := parse_interspersed(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: java callspecs [flags] <file.java>")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := load_call_spec_config(*spec)
+	if err != nil {
+		return fmt.Errorf("could not load call spec config: %w", err)
+	}
+	if *suffix != "" {
+		cfg.Suffix = *suffix
+	}
+
+	if *owner == "" {
+		if cfg.From_db {
+			return fmt.Errorf("from_db needs -owner")
+		}
+		_, methods, err := parse_java_methods(string(data))
+		if err != nil {
+			return fmt.Errorf("%s: %w", positional[0], err)
+		}
+		for _, cs := range generate_call_specs(cfg, methods) {
+			fmt.Printf("%s\n/\n", cs.ddl)
+			if cs.test_sql != "" {
+				fmt.Printf("-- test: %s\n", cs.test_sql)
+			}
+		}
+		return nil
+	}
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return deploy_call_specs(ctx, db, target, string(data), cfg)
+}
@@ -0,0 +1,398 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+// default_java_sql_types maps Java parameter and return types to the SQL
+// types of the generated call specs. call_spec_config.Types overrides it.
+var default_java_sql_types = map[string]string{
+	"java.lang.String":       "VARCHAR2",
+	"java.sql.Clob":          "CLOB",
+	"oracle.sql.CLOB":        "CLOB",
+	"oracle.jdbc.OracleClob": "CLOB",
+	"java.sql.Blob":          "BLOB",
+	"oracle.sql.BLOB":        "BLOB",
+	"oracle.jdbc.OracleBlob": "BLOB",
+	"byte[]":                 "RAW",
+	"int":                    "NUMBER",
+	"long":                   "NUMBER",
+	"short":                  "NUMBER",
+	"byte":                   "NUMBER",
+	"float":                  "NUMBER",
+	"double":                 "NUMBER",
+	"java.lang.Integer":      "NUMBER",
+	"java.lang.Long":         "NUMBER",
+	"java.lang.Short":        "NUMBER",
+	"java.lang.Float":        "NUMBER",
+	"java.lang.Double":       "NUMBER",
+	"java.math.BigDecimal":   "NUMBER",
+	"oracle.sql.NUMBER":      "NUMBER",
+	"java.sql.Date":          "DATE",
+	"oracle.sql.DATE":        "DATE",
+	"java.sql.Timestamp":     "TIMESTAMP",
+	"oracle.sql.TIMESTAMP":   "TIMESTAMP",
+}
+
+// smoke_test_values are the arguments used to smoke-test generated functions.
+var smoke_test_values = map[string]string{
+	"VARCHAR2":  "'test'",
+	"CLOB":      "TO_CLOB('test')",
+	"BLOB":      "TO_BLOB(HEXTORAW('00'))",
+	"RAW":       "HEXTORAW('00')",
+	"NUMBER":    "1",
+	"DATE":      "SYSDATE",
+	"TIMESTAMP": "SYSTIMESTAMP",
+	"BOOLEAN":   "TRUE",
+	"JSON":      "JSON('{}')",
+}
+
+// call_spec_config is the call_specs section of a deploy manifest unit or
+// of the file given to "java callspecs -spec" and "mle callspecs -spec".
+type call_spec_config struct {
+	Suffix  string            `yaml:"suffix"`  // appended to the method name, e.g. "_pl"
+	Methods []string          `yaml:"methods"` // only these methods; default every public static method (or export)
+	Types   map[string]string `yaml:"types"`   // Java or JSDoc type -> SQL type, added to the defaults
+	From_db bool              `yaml:"from_db"` // Java: read signatures from ALL_JAVA_METHODS after compile
+	No_test bool              `yaml:"no_test"` // skip the smoke tests of generated functions
+	Env     string            `yaml:"env"`     // JavaScript: MLE environment of the call specs
+}
+
+func load_call_spec_config(path string) (*call_spec_config, error) {
+	cfg := &call_spec_config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *call_spec_config) sql_type(java_type string) (string, bool) {
+	if t, ok := cfg.Types[java_type]; ok {
+		return t, true
+	}
+	t, ok := default_java_sql_types[java_type]
+	return t, ok
+}
+
+// java_method is a public static method; types are fully qualified as the
+// NAME clause of a call spec needs them.
+type java_method struct {
+	class       string // e.g. com.example.Util
+	name        string
+	param_names []string
+	param_types []string
+	returns     string // "void" for procedures
+}
+
+// signature is the NAME clause string, e.g.
+// 'Util.upper(java.lang.String) return java.lang.String'.
+func (m java_method) signature() string {
+	s := fmt.Sprintf("%s.%s(%s)", m.class, m.name, strings.Join(m.param_types, ", "))
+	if m.returns != "void" {
+		s += " return " + m.returns
+	}
+	return s
+}
+
+var (
+	java_comment_re = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	java_package_re = regexp.MustCompile(`(?m)^\s*package\s+([\w.]+)\s*;`)
+	java_import_re  = regexp.MustCompile(`(?m)^\s*import\s+([\w.]+)(\.\*)?\s*;`)
+	java_static_re  = regexp.MustCompile(`\bpublic\s+(?:(?:final|synchronized|strictfp)\s+)*static\s+` +
+		`(?:(?:final|synchronized|strictfp)\s+)*([\w.$]+(?:\s*\[\s*\])*)\s+(\w+)\s*\(([^)]*)\)`)
+	java_annotation_re = regexp.MustCompile(`@\w+(\([^)]*\))?`)
+)
+
+var java_lang_types = map[string]bool{
+	"String": true, "Integer": true, "Long": true, "Short": true, "Byte": true, "Float": true,
+	"Double": true, "Boolean": true, "Character": true, "Object": true,
+}
+
+var java_primitive_types = map[string]bool{
+	"void": true, "int": true, "long": true, "short": true, "byte": true, "float": true,
+	"double": true, "boolean": true, "char": true,
+}
+
+// java_type_resolver qualifies simple type names with the package and the
+// imports of a source file.
+type java_type_resolver struct {
+	pkg       string
+	imports   map[string]string // simple name -> qualified
+	wildcards []string          // packages imported with .*
+}
+
+func new_java_type_resolver(src string) *java_type_resolver {
+	r := &java_type_resolver{imports: map[string]string{}}
+	if m := java_package_re.FindStringSubmatch(src); m != nil {
+		r.pkg = m[1]
+	}
+	for _, m := range java_import_re.FindAllStringSubmatch(src, -1) {
+		if m[2] != "" {
+			r.wildcards = append(r.wildcards, m[1])
+			continue
+		}
+		r.imports[m[1][strings.LastIndex(m[1], ".")+1:]] = m[1]
+	}
+	return r
+}
+
+// qualify turns "Clob" into "java.sql.Clob" given "import java.sql.*;".
+// A simple name imported by several wildcards resolves to the first one
+// that the default type mapping knows.
+func (r *java_type_resolver) qualify(t string) string {
+	t = strings.Join(strings.Fields(t), "")
+	if base, ok := strings.CutSuffix(t, "[]"); ok {
+		return r.qualify(base) + "[]"
+	}
+	if base, ok := strings.CutSuffix(t, "..."); ok {
+		return r.qualify(base) + "[]"
+	}
+	if java_primitive_types[t] || strings.Contains(t, ".") {
+		return t
+	}
+	if q, ok := r.imports[t]; ok {
+		return q
+	}
+	if java_lang_types[t] {
+		return "java.lang." + t
+	}
+	for _, w := range r.wildcards {
+		if _, ok := default_java_sql_types[w+"."+t]; ok {
+			return w + "." + t
+		}
+	}
+	if len(r.wildcards) == 1 {
+		return r.wildcards[0] + "." + t
+	}
+	if r.pkg != "" {
+		return r.pkg + "." + t
+	}
+	return t
+}
+
+// parse_java_methods returns the public static methods of the public class
+// of a Java source file.
+func parse_java_methods(src string) (string, []java_method, error) {
+	code := java_comment_re.ReplaceAllString(src, "")
+	m := java_class_re.FindStringSubmatch(code)
+	if m == nil {
+		return "", nil, fmt.Errorf("no public class found")
+	}
+	r := new_java_type_resolver(code)
+	class := m[1]
+	if r.pkg != "" {
+		class = r.pkg + "." + class
+	}
+
+	var methods []java_method
+	for _, sm := range java_static_re.FindAllStringSubmatch(code, -1) {
+		jm := java_method{class: class, name: sm[2], returns: r.qualify(sm[1])}
+		params := strings.TrimSpace(java_annotation_re.ReplaceAllString(sm[3], ""))
+		if params != "" {
+			for _, p := range strings.Split(params, ",") {
+				words := strings.Fields(strings.ReplaceAll(p, "final ", ""))
+				if len(words) < 2 {
+					return "", nil, fmt.Errorf("%s: cannot parse parameter %q", jm.name, p)
+				}
+				name := words[len(words)-1]
+				typ := strings.Join(words[:len(words)-1], "")
+				for strings.HasSuffix(name, "[]") { // "String args[]"
+					name = strings.TrimSuffix(name, "[]")
+					typ += "[]"
+				}
+				jm.param_names = append(jm.param_names, name)
+				jm.param_types = append(jm.param_types, r.qualify(typ))
+			}
+		}
+		methods = append(methods, jm)
+	}
+	return class, methods, nil
+}
+
+// java_methods_from_db reads the public static methods of a resolved class
+// from ALL_JAVA_METHODS and ALL_JAVA_ARGUMENTS. Class names use "/" in the
+// dictionary ("com/example/Util").
+func java_methods_from_db(ctx context.Context, db *sql.DB, owner, class string) ([]java_method, error) {
+	dict_name := strings.ReplaceAll(class, ".", "/")
+	rows, err := db.QueryContext(ctx, `
+		SELECT m.method_index, m.method_name, m.return_type, m.return_class, m.return_array_depth,
+		       a.argument_position, a.argument_type, a.argument_class, a.array_depth
+		FROM   all_java_methods m
+		LEFT JOIN all_java_arguments a
+		       ON a.owner = m.owner AND a.name = m.name AND a.method_index = m.method_index
+		WHERE  m.owner = :1
+		  AND  m.name = :2
+		  AND  m.accessibility = 'PUBLIC'
+		  AND  m.is_static = 'YES'
+		ORDER BY m.method_index, a.argument_position`, owner, dict_name)
+	if err != nil {
+		return nil, fmt.Errorf("query java methods failed: %w", err)
+	}
+	defer rows.Close()
+
+	dict_type := func(kind, class sql.NullString, depth sql.NullInt64) string {
+		t := kind.String
+		if t == "class" {
+			t = strings.ReplaceAll(class.String, "/", ".")
+		}
+		return t + strings.Repeat("[]", int(depth.Int64))
+	}
+
+	var methods []java_method
+	last := -1
+	for rows.Next() {
+		var index int
+		var name string
+		var ret_type, ret_class, arg_type, arg_class sql.NullString
+		var ret_depth, arg_pos, arg_depth sql.NullInt64
+		if err := rows.Scan(&index, &name, &ret_type, &ret_class, &ret_depth,
+			&arg_pos, &arg_type, &arg_class, &arg_depth); err != nil {
+			return nil, err
+		}
+		if index != last {
+			methods = append(methods, java_method{class: class, name: name, returns: dict_type(ret_type, ret_class, ret_depth)})
+			last = index
+		}
+		if arg_pos.Valid {
+			jm := &methods[len(methods)-1]
+			jm.param_names = append(jm.param_names, fmt.Sprintf("p%d", len(jm.param_names)+1))
+			jm.param_types = append(jm.param_types, dict_type(arg_type, arg_class, arg_depth))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(methods) == 0 {
+		return nil, fmt.Errorf("no public static methods of %s.%s in ALL_JAVA_METHODS (is the class resolved?)", owner, class)
+	}
+	return methods, nil
+}
+
+// call_spec is a generated wrapper and its smoke test ("" if none).
+type call_spec struct {
+	name     string
+	ddl      string
+	test_sql string
+}
+
+// generate_call_specs builds one FUNCTION (or PROCEDURE for void methods)
+// per method. Methods with unmapped types or an overloaded name are skipped
+// with a warning, since standalone subprograms cannot be overloaded.
+func generate_call_specs(cfg *call_spec_config, methods []java_method) []call_spec {
+	wanted := map[string]bool{}
+	for _, name := range cfg.Methods {
+		wanted[name] = true
+	}
+	count := map[string]int{}
+	warned := map[string]bool{}
+	for _, jm := range methods {
+		count[jm.name]++
+	}
+
+	var specs []call_spec
+	for _, jm := range methods {
+		if len(wanted) > 0 && !wanted[jm.name] {
+			continue
+		}
+		if count[jm.name] > 1 {
+			if !warned[jm.name] {
+				fmt.Printf("⚠️ %s.%s is overloaded; skipping (write its call specs by hand)\n", jm.class, jm.name)
+				warned[jm.name] = true
+			}
+			continue
+		}
+
+		name := strings.ToUpper(jm.name + cfg.Suffix)
+		var params, args []string
+		unmapped := ""
+		for i, jt := range jm.param_types {
+			st, ok := cfg.sql_type(jt)
+			if !ok {
+				unmapped = jt
+				break
+			}
+			params = append(params, fmt.Sprintf("p_%s %s", strings.ToLower(jm.param_names[i]), st))
+			args = append(args, smoke_test_values[st])
+		}
+		ret := ""
+		if unmapped == "" && jm.returns != "void" {
+			var ok bool
+			if ret, ok = cfg.sql_type(jm.returns); !ok {
+				unmapped = jm.returns
+			}
+		}
+		if unmapped != "" {
+			fmt.Printf("⚠️ %s.%s: no SQL type for %s; skipping (add it to types)\n", jm.class, jm.name, unmapped)
+			continue
+		}
+
+		param_list := ""
+		if len(params) > 0 {
+			param_list = "(" + strings.Join(params, ", ") + ")"
+		}
+		cs := call_spec{name: name}
+		if jm.returns == "void" {
+			cs.ddl = fmt.Sprintf("CREATE OR REPLACE PROCEDURE %s%s\nAS LANGUAGE JAVA\nNAME %s;",
+				name, param_list, quote_literal(jm.signature()))
+		} else {
+			cs.ddl = fmt.Sprintf("CREATE OR REPLACE FUNCTION %s%s\n  RETURN %s\nAS LANGUAGE JAVA\nNAME %s;",
+				name, param_list, ret, quote_literal(jm.signature()))
+			if !cfg.No_test && !slices.Contains(args, "") {
+				call := name
+				if len(args) > 0 {
+					call += "(" + strings.Join(args, ", ") + ")"
+				}
+				cs.test_sql = fmt.Sprintf("SELECT %s FROM dual", call)
+			}
+		}
+		specs = append(specs, cs)
+	}
+	sort.SliceStable(specs, func(i, j int) bool { return specs[i].name < specs[j].name })
+	return specs
+}
+
+// deploy_call_specs generates, compiles and smoke-tests the call specs of
+// a Java source that has just been compiled in owner.
+func deploy_call_specs(ctx context.Context, db *sql.DB, owner, src string, cfg *call_spec_config) error {
+	class, methods, err := parse_java_methods(src)
+	if err != nil {
+		return err
+	}
+	if cfg.From_db {
+		if methods, err = java_methods_from_db(ctx, db, owner, class); err != nil {
+			return err
+		}
+	}
+	specs := generate_call_specs(cfg, methods)
+	if len(specs) == 0 {
+		return fmt.Errorf("no call specs generated for %s", class)
+	}
+	for _, cs := range specs {
+		fmt.Printf("🔗 call spec %s -> %s\n", cs.name, class)
+		if _, err := deploy_ddl(ctx, db, owner, cs.ddl, nil); err != nil {
+			return err
+		}
+		if cs.test_sql != "" {
+			if err := smoke_test(ctx, db, cs.name, cs.test_sql); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// java_object is a JAVA SOURCE, CLASS or RESOURCE of a schema.
+type java_object struct {
+	obj_type  string // JAVA SOURCE, JAVA CLASS or JAVA RESOURCE
+	name      string // long name, e.g. com/example/Util
+	short     string // ALL_OBJECTS name; differs from name when it is too long
+	status    string
+	source    string // the JAVA SOURCE a class was compiled from, "" if loaded
+	timestamp string
+}
+
+// java_inventory lists the Java objects of owner ordered by type and long
+// name. DBMS_JAVA.DERIVEDFROM gives the source of compiled classes.
+func java_inventory(ctx context.Context, db *sql.DB, owner string) ([]java_object, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT object_type, DBMS_JAVA.LONGNAME(object_name), object_name, status,
+		       CASE WHEN object_type = 'JAVA CLASS'
+		            THEN DBMS_JAVA.LONGNAME(DBMS_JAVA.DERIVEDFROM(object_name, owner, object_type)) END,
+		       TO_CHAR(last_ddl_time, 'YYYY-MM-DD HH24:MI:SS')
+		FROM   all_objects
+		WHERE  owner = :1
+		  AND  object_type IN ('JAVA SOURCE', 'JAVA CLASS', 'JAVA RESOURCE')
+		ORDER BY object_type DESC, 2`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query java objects failed: %w", err)
+	}
+	defer rows.Close()
+
+	var objs []java_object
+	for rows.Next() {
+		var o java_object
+		var source sql.NullString
+		if err := rows.Scan(&o.obj_type, &o.name, &o.short, &o.status, &source, &o.timestamp); err != nil {
+			return nil, err
+		}
+		o.source = source.String
+		objs = append(objs, o)
+	}
+	return objs, rows.Err()
+}
+
+// find_java_object looks an object up by long or short name; kind limits it
+// to one type when set.
+func find_java_object(objs []java_object, name, kind string) (java_object, error) {
+	var found []java_object
+	for _, o := range objs {
+		if kind != "" && o.obj_type != kind {
+			continue
+		}
+		if o.name == name || o.short == name || strings.ReplaceAll(o.name, "/", ".") == name {
+			found = append(found, o)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return java_object{}, fmt.Errorf("no Java object %q", name)
+	case 1:
+		return found[0], nil
+	}
+	return java_object{}, fmt.Errorf("%q is ambiguous (%d objects); use -type", name, len(found))
+}
+
+var java_type_flags = map[string]string{
+	"":         "",
+	"source":   "JAVA SOURCE",
+	"class":    "JAVA CLASS",
+	"resource": "JAVA RESOURCE",
+}
+
+func run_java_list(args []string) error {
+	fs := flag.NewFlagSet("java list", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema to list")
+	kind := fs.String("type", "", "source, class or resource (default: all)")
+	invalid_only := fs.Bool("invalid", false, "only objects that are not VALID")
+	fs.Parse(args)
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	obj_type, ok := java_type_flags[*kind]
+	if !ok {
+		return fmt.Errorf("unknown -type %q (source, class or resource)", *kind)
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	objs, err := java_inventory(ctx, db, target)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("%-13s %-50s %-8s %-30s %s\n", "TYPE", "NAME", "STATUS", "SOURCE", "LAST DDL")
+	counts := map[string]int{}
+	invalid := 0
+	for _, o := range objs {
+		if (obj_type != "" && o.obj_type != obj_type) || (*invalid_only && o.status == "VALID") {
+			continue
+		}
+		name := o.name
+		if o.short != o.name {
+			name += " (" + o.short + ")"
+		}
+		source := o.source
+		if source == "" {
+			source = "-"
+		}
+		fmt.Printf("%-13s %-50s %-8s %-30s %s\n", o.obj_type, name, o.status, source, o.timestamp)
+		counts[o.obj_type]++
+		if o.status != "VALID" {
+			invalid++
+		}
+	}
+	fmt.Printf("📊 sources=%d, classes=%d, resources=%d, not valid=%d\n",
+		counts["JAVA SOURCE"], counts["JAVA CLASS"], counts["JAVA RESOURCE"], invalid)
+	return nil
+}
+
+func run_java_drop(args []string) error {
+	fs := flag.NewFlagSet("java drop", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema holding the objects")
+	kind := fs.String("type", "", "source, class or resource, when a name is ambiguous")
+	dry_run := fs.Bool("dry-run", false, "only print the DROP statements and the classes they remove")
+	positional := parse_interspersed(fs, args)
+	if len(positional) == 0 {
+		return fmt.Errorf("usage: java drop -owner X [flags] <name>...")
+	}
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	obj_type, ok := java_type_flags[*kind]
+	if !ok {
+		return fmt.Errorf("unknown -type %q (source, class or resource)", *kind)
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	objs, err := java_inventory(ctx, db, target)
+	if err != nil {
+		return err
+	}
+
+	var dropped []java_object
+	for _, name := range positional {
+		o, err := find_java_object(objs, name, obj_type)
+		if err != nil {
+			return err
+		}
+		if o.obj_type == "JAVA CLASS" && o.source != "" {
+			return fmt.Errorf("%s was compiled from JAVA SOURCE %s; drop the source instead", o.name, o.source)
+		}
+		// DROP JAVA SOURCE also drops the classes compiled from it
+		for _, c := range objs {
+			if o.obj_type == "JAVA SOURCE" && c.source == o.name {
+				fmt.Printf("🔗 %s %s (derived from %s)\n", c.obj_type, c.name, o.name)
+				dropped = append(dropped, c)
+			}
+		}
+		stmt := fmt.Sprintf("DROP %s %s", o.obj_type, java_object_name(target, o.short))
+		if err := exec_or_print(ctx, db, *dry_run, stmt); err != nil {
+			return err
+		}
+		dropped = append(dropped, o)
+	}
+	if *dry_run {
+		return nil
+	}
+
+	after, err := java_inventory(ctx, db, target)
+	if err != nil {
+		return err
+	}
+	left := 0
+	for _, d := range dropped {
+		if _, err := find_java_object(after, d.name, d.obj_type); err == nil {
+			fmt.Printf("❌ %s %s still exists\n", d.obj_type, d.name)
+			left++
+		}
+	}
+	fmt.Printf("📊 dropped=%d, still present=%d\n", len(dropped)-left, left)
+	if left > 0 {
+		return fmt.Errorf("%d object(s) were not dropped", left)
+	}
+	return nil
+}
+
+func run_java_resolve(args []string) error {
+	fs := flag.NewFlagSet("java resolve", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema holding the classes")
+	resolver := fs.String("resolver", "", "resolver spec to set, e.g. ((* APP)(* PUBLIC)) (default: keep each class's resolver)")
+	all := fs.Bool("all", false, "resolve every class, not only the INVALID ones")
+	positional := parse_interspersed(fs, args)
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	if *resolver != "" {
+		if *resolver, err = check_resolver(*resolver); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	objs, err := java_inventory(ctx, db, target)
+	if err != nil {
+		return err
+	}
+
+	// named classes, or the INVALID sources and classes (-all: every class)
+	var todo []java_object
+	for _, name := range positional {
+		o, err := find_java_object(objs, name, "")
+		if err != nil {
+			return err
+		}
+		todo = append(todo, o)
+	}
+	if len(positional) == 0 {
+		for _, o := range objs {
+			if o.obj_type == "JAVA RESOURCE" {
+				continue
+			}
+			if o.status != "VALID" || (*all && o.obj_type == "JAVA CLASS") {
+				todo = append(todo, o)
+			}
+		}
+	}
+	if len(todo) == 0 {
+		fmt.Println("✅ no Java objects to resolve")
+		return nil
+	}
+
+	// sources first: compiling a source re-creates its classes
+	resolver_clause := ""
+	if *resolver != "" {
+		resolver_clause = " RESOLVER " + *resolver
+	}
+	var classes []string
+	for _, o := range todo {
+		switch o.obj_type {
+		case "JAVA SOURCE":
+			stmt := fmt.Sprintf("ALTER JAVA SOURCE %s%s COMPILE", java_object_name(target, o.short), resolver_clause)
+			if _, err := db.ExecContext(ctx, stmt); err != nil && !is_compiled_with_errors(err) {
+				fmt.Printf("❌ %s: %v\n", stmt, err)
+				continue
+			}
+			fmt.Printf("🔧 %s\n", stmt)
+		case "JAVA CLASS":
+			classes = append(classes, o.short)
+		}
+	}
+	// classes that refer to each other may need a second pass
+	for pass := 1; pass <= 2 && len(classes) > 0; pass++ {
+		var failed []string
+		for _, c := range classes {
+			stmt := fmt.Sprintf("ALTER JAVA CLASS %s%s RESOLVE", java_object_name(target, c), resolver_clause)
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				failed = append(failed, c)
+				continue
+			}
+			fmt.Printf("🔧 %s\n", stmt)
+		}
+		classes = failed
+	}
+
+	after, err := java_inventory(ctx, db, target)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%-13s %-50s %-8s %s\n", "TYPE", "NAME", "BEFORE", "AFTER")
+	var still []java_object
+	gone := 0
+	for _, o := range todo {
+		now := "(gone)"
+		mark := "ℹ️"
+		if a, err := find_java_object(after, o.name, o.obj_type); err != nil {
+			gone++
+		} else {
+			now, mark = a.status, "✅"
+			if a.status != "VALID" {
+				mark = "❌"
+				still = append(still, a)
+			}
+		}
+		fmt.Printf("%-13s %-50s %-8s %-8s %s\n", o.obj_type, o.name, o.status, now, mark)
+	}
+	fmt.Printf("📊 resolved=%d, still invalid=%d, gone=%d\n", len(todo)-len(still)-gone, len(still), gone)
+	for _, o := range still {
+		fmt.Printf("\n🧩 %s %s\n", o.obj_type, o.name)
+		if _, err := dump_compile_errors(ctx, db, target, o.obj_type, o.short, nil); err != nil {
+			return err
+		}
+	}
+	if len(still) > 0 {
+		return fmt.Errorf("%d Java object(s) still invalid", len(still))
+	}
+	return nil
+}
@@ -0,0 +1,383 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/godror/godror"
+)
+
+// java_stage_table holds class files and resources while CREATE JAVA ...
+// USING BLOB reads them, the way loadjava stages its uploads. It is a
+// temporary table in the owner's schema and is dropped afterwards if the
+// load created it.
+const java_stage_table = "GO_ORACLE_JAVA_STAGE"
+
+// java_blob is one class file or resource to load.
+type java_blob struct {
+	kind   string // "CLASS" or "RESOURCE"
+	name   string // class name as stored ("com/example/Util") or resource path
+	source string // file, or jar!entry
+	data   []byte
+}
+
+// read_java_blobs reads .class files, .jar/.zip archives (every .class
+// entry is a class, every other file a resource; META-INF/MANIFEST.MF is
+// skipped like loadjava does) and any other file as a resource.
+func read_java_blobs(path string) ([]java_blob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".class":
+		name, err := class_file_name(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return []java_blob{{"CLASS", name, path, data}}, nil
+	case ".jar", ".zip":
+		return read_jar(path, data)
+	case ".java":
+		return nil, fmt.Errorf("%s: Java sources are compiled with deploy, not loaded", path)
+	}
+	return []java_blob{{"RESOURCE", filepath.ToSlash(filepath.Base(path)), path, data}}, nil
+}
+
+func read_jar(path string, data []byte) ([]java_blob, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var blobs []java_blob
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || strings.EqualFold(f.Name, "META-INF/MANIFEST.MF") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("%s!%s: %w", path, f.Name, err)
+		}
+		entry, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s!%s: %w", path, f.Name, err)
+		}
+		source := path + "!" + f.Name
+		if strings.HasSuffix(f.Name, ".class") {
+			name, err := class_file_name(entry)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", source, err)
+			}
+			blobs = append(blobs, java_blob{"CLASS", name, source, entry})
+			continue
+		}
+		blobs = append(blobs, java_blob{"RESOURCE", f.Name, source, entry})
+	}
+	return blobs, nil
+}
+
+// class_file_name reads this_class from a class file's constant pool, e.g.
+// "com/example/Util". CREATE JAVA CLASS takes the name from the bytes too.
+func class_file_name(data []byte) (string, error) {
+	r := bytes.NewReader(data)
+	var head struct {
+		Magic        uint32
+		Minor, Major uint16
+		Count        uint16
+	}
+	if err := binary.Read(r, binary.BigEndian, &head); err != nil || head.Magic != 0xCAFEBABE {
+		return "", fmt.Errorf("not a class file")
+	}
+
+	utf8 := map[uint16]string{}
+	class_name_index := map[uint16]uint16{}
+	u2 := func() uint16 {
+		var v uint16
+		binary.Read(r, binary.BigEndian, &v)
+		return v
+	}
+	for i := uint16(1); i < head.Count; i++ {
+		tag, err := r.ReadByte()
+		if err != nil {
+			return "", fmt.Errorf("truncated constant pool")
+		}
+		switch tag {
+		case 1: // Utf8
+			b := make([]byte, u2())
+			if _, err := io.ReadFull(r, b); err != nil {
+				return "", fmt.Errorf("truncated constant pool")
+			}
+			utf8[i] = string(b)
+		case 7: // Class
+			class_name_index[i] = u2()
+		case 8, 16, 19, 20: // String, MethodType, Module, Package
+			r.Seek(2, io.SeekCurrent)
+		case 15: // MethodHandle
+			r.Seek(3, io.SeekCurrent)
+		case 3, 4, 9, 10, 11, 12, 17, 18:
+			r.Seek(4, io.SeekCurrent)
+		case 5, 6: // Long, Double take two slots
+			r.Seek(8, io.SeekCurrent)
+			i++
+		default:
+			return "", fmt.Errorf("unknown constant pool tag %d", tag)
+		}
+	}
+	u2() // access_flags
+	this_class := u2()
+	name, ok := utf8[class_name_index[this_class]]
+	if !ok {
+		return "", fmt.Errorf("this_class not found")
+	}
+	return name, nil
+}
+
+// default_resolver is loadjava's default: the owner's schema, then PUBLIC.
+func default_resolver(owner string) string {
+	return fmt.Sprintf("((* %s)(* PUBLIC))", owner)
+}
+
+// check_resolver accepts a resolver specification like
+// ((com/example/* APP)(* PUBLIC)(* -)) and rejects anything that could end
+// the statement early.
+func check_resolver(spec string) (string, error) {
+	spec = strings.TrimSpace(spec)
+	depth := 0
+	for _, c := range spec {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ';', '\'':
+			depth = -1
+		}
+		if depth < 0 {
+			break
+		}
+	}
+	if depth != 0 || !strings.HasPrefix(spec, "((") {
+		return "", fmt.Errorf("invalid resolver %q, expected e.g. ((* OWNER)(* PUBLIC))", spec)
+	}
+	return spec, nil
+}
+
+// java_object_name is how a class or resource is addressed in DDL.
+func java_object_name(owner, name string) string {
+	return quote_identifier(owner) + "." + quote_identifier(name)
+}
+
+// load_java_blobs stages and creates every blob in owner. Classes are
+// created unresolved; resolve_java_classes resolves them once all are in.
+func load_java_blobs(ctx context.Context, db *sql.DB, owner, resolver string, blobs []java_blob) error {
+	stage := owner + "." + java_stage_table
+	var exists int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM all_tables WHERE owner = :1 AND table_name = :2`,
+		owner, java_stage_table).Scan(&exists); err != nil {
+		return fmt.Errorf("check stage table failed: %w", err)
+	}
+	// a table left by an earlier or concurrent load is used and kept; only
+	// a table created here is dropped
+	created := exists == 0
+	if created {
+		if _, err := db.ExecContext(ctx, "CREATE GLOBAL TEMPORARY TABLE "+stage+
+			" (name VARCHAR2(4000) PRIMARY KEY, data BLOB) ON COMMIT PRESERVE ROWS"); err != nil {
+			return fmt.Errorf("create stage table failed: %w", err)
+		}
+	}
+	defer func() {
+		if _, err := db.ExecContext(ctx, "TRUNCATE TABLE "+stage); err != nil {
+			fmt.Printf("⚠️ truncate %s failed: %v\n", stage, err)
+		}
+		if !created {
+			return
+		}
+		if _, err := db.ExecContext(ctx, "DROP TABLE "+stage+" PURGE"); err != nil {
+			fmt.Printf("⚠️ drop %s failed: %v\n", stage, err)
+		}
+	}()
+
+	for _, b := range blobs {
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+stage+" WHERE name = :1", b.name); err != nil {
+			return fmt.Errorf("stage %s failed: %w", b.source, err)
+		}
+		if _, err := db.ExecContext(ctx, "INSERT INTO "+stage+" (name, data) VALUES (:1, :2)",
+			b.name, godror.Lob{Reader: bytes.NewReader(b.data)}); err != nil {
+			return fmt.Errorf("stage %s failed: %w", b.source, err)
+		}
+		using := fmt.Sprintf("USING BLOB (SELECT data FROM %s WHERE name = %s)", stage, quote_literal(b.name))
+		var stmt string
+		if b.kind == "CLASS" {
+			stmt = fmt.Sprintf("CREATE OR REPLACE JAVA CLASS RESOLVER %s %s", resolver, using)
+		} else {
+			stmt = fmt.Sprintf("CREATE OR REPLACE JAVA RESOURCE NAMED %s %s", java_object_name(owner, b.name), using)
+		}
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("load %s failed: %w", b.source, err)
+		}
+		fmt.Printf("📦 loaded JAVA %s %s (%d bytes)\n", b.kind, b.name, len(b.data))
+	}
+	return nil
+}
+
+// resolve_java_classes resolves the loaded classes. Classes that refer to
+// each other may need a second pass, so unresolved ones are retried once.
+func resolve_java_classes(ctx context.Context, db *sql.DB, owner, resolver string, names []string) {
+	pending := names
+	for pass := 1; pass <= 2 && len(pending) > 0; pass++ {
+		var failed []string
+		for _, name := range pending {
+			stmt := fmt.Sprintf("ALTER JAVA CLASS %s RESOLVER %s RESOLVE", java_object_name(owner, name), resolver)
+			if _, err := db.ExecContext(ctx, stmt); err != nil {
+				failed = append(failed, name)
+			}
+		}
+		pending = failed
+	}
+}
+
+// java_object_status returns the ALL_OBJECTS status and stored object name
+// of each Java object of owner by its long name; names over the identifier
+// limit are stored under a short name that DBMS_JAVA.LONGNAME expands.
+func java_object_status(ctx context.Context, db *sql.DB, owner string) (map[string][2]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT DBMS_JAVA.LONGNAME(object_name), object_name, status
+		FROM   all_objects
+		WHERE  owner = :1
+		  AND  object_type IN ('JAVA CLASS', 'JAVA RESOURCE', 'JAVA SOURCE')`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query java objects failed: %w", err)
+	}
+	defer rows.Close()
+	status := map[string][2]string{}
+	for rows.Next() {
+		var long_name, object_name, st string
+		if err := rows.Scan(&long_name, &object_name, &st); err != nil {
+			return nil, err
+		}
+		status[long_name] = [2]string{object_name, st}
+	}
+	return status, rows.Err()
+}
+
+func run_java_load(args []string) error {
+	fs := flag.NewFlagSet("java load", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema to load the classes and resources into")
+	resolver := fs.String("resolver", "", "resolver specification (default ((* OWNER)(* PUBLIC)))")
+	no_resolve := fs.Bool("no-resolve", false, "load only; leave the classes unresolved")
+	dry_run := fs.Bool("dry-run", false, "list what would be loaded")
+	files := parse_interspersed(fs, args)
+	if len(files) == 0 {
+		return fmt.Errorf("usage: java load [flags] <file.class|file.jar|resource>...")
+	}
+
+	var blobs []java_blob
+	for _, f := range files {
+		b, err := read_java_blobs(f)
+		if err != nil {
+			return err
+		}
+		blobs = append(blobs, b...)
+	}
+	sort.SliceStable(blobs, func(i, j int) bool { return blobs[i].kind < blobs[j].kind })
+	for _, b := range blobs {
+		fmt.Printf("🔎 JAVA %-8s %-50s %s\n", b.kind, b.name, b.source)
+	}
+	if *dry_run {
+		return nil
+	}
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	if *resolver == "" {
+		*resolver = default_resolver(target)
+	}
+	spec, err := check_resolver(*resolver)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := check_javavm(ctx, db); err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, "ALTER SESSION SET CURRENT_SCHEMA = "+target); err != nil {
+		return fmt.Errorf("set current_schema failed: %w", err)
+	}
+
+	if err := load_java_blobs(ctx, db, target, spec, blobs); err != nil {
+		return err
+	}
+	var classes []string
+	for _, b := range blobs {
+		if b.kind == "CLASS" {
+			classes = append(classes, b.name)
+		}
+	}
+	if !*no_resolve {
+		fmt.Printf("🔗 resolving %d class(es) with %s\n", len(classes), spec)
+		resolve_java_classes(ctx, db, target, spec, classes)
+	}
+
+	err = report_java_status(ctx, db, target, blobs)
+	// the loaded code runs with the owner's Java permissions; show them so
+	// a missing grant is seen before the first call fails
+	fmt.Println()
+	if policy_err := print_java_policy(ctx, db, target); policy_err != nil && err == nil {
+		err = policy_err
+	}
+	return err
+}
+
+// report_java_status prints the ALL_OBJECTS status of the loaded objects
+// and the resolver errors of classes that did not resolve.
+func report_java_status(ctx context.Context, db *sql.DB, owner string, blobs []java_blob) error {
+	status, err := java_object_status(ctx, db, owner)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\n%-10s %-55s %s\n", "TYPE", "NAME", "STATUS")
+	var invalid []java_blob
+	for _, b := range blobs {
+		st := status[b.name][1]
+		mark := "✅"
+		switch st {
+		case "":
+			st, mark = "(missing)", "❌"
+		case "INVALID":
+			mark = "❌"
+			invalid = append(invalid, b)
+		}
+		fmt.Printf("%-10s %-55s %-9s %s\n", b.kind, b.name, st, mark)
+	}
+	fmt.Printf("📊 loaded=%d, unresolved=%d\n", len(blobs), len(invalid))
+	for _, b := range invalid {
+		fmt.Printf("\n🧩 JAVA CLASS %s\n", b.name)
+		if _, err := dump_compile_errors(ctx, db, owner, "JAVA CLASS", status[b.name][0], nil); err != nil {
+			return err
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%d class(es) did not resolve", len(invalid))
+	}
+	return nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// java_permission is one entry of the java_permissions section of a deploy
+// manifest, applied with DBMS_JAVA.GRANT_PERMISSION or REVOKE_PERMISSION.
+type java_permission struct {
+	Type    string `yaml:"type"`    // permission class, e.g. java.io.FilePermission or SYS:java.net.SocketPermission
+	Name    string `yaml:"name"`    // target, e.g. /tmp/* or host:port
+	Action  string `yaml:"action"`  // e.g. read,write or connect,resolve
+	Grantee string `yaml:"grantee"` // default: the schema the code is deployed to
+	Revoke  bool   `yaml:"revoke"`  // revoke a permission granted earlier
+}
+
+func (p java_permission) String() string {
+	return fmt.Sprintf("%s %q %q", p.Type, p.Name, p.Action)
+}
+
+// check_javavm reports the JAVAVM component from DBA_REGISTRY and fails
+// unless it is installed and VALID.
+func check_javavm(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT comp_id, comp_name, version, status
+		FROM   dba_registry
+		WHERE  comp_id IN ('JAVAVM', 'CATJAVA', 'XML')
+		ORDER BY comp_id`)
+	if err != nil {
+		return fmt.Errorf("query dba_registry failed: %w", err)
+	}
+	defer rows.Close()
+
+	javavm := ""
+	for rows.Next() {
+		var id, name, version, status string
+		if err := rows.Scan(&id, &name, &version, &status); err != nil {
+			return err
+		}
+		fmt.Printf("☕ %-8s %-40s %-12s %s\n", id, name, version, status)
+		if id == "JAVAVM" {
+			javavm = status
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	switch javavm {
+	case "":
+		return fmt.Errorf("JAVAVM is not installed in this container (no DBA_REGISTRY entry)")
+	case "VALID":
+		return nil
+	}
+	return fmt.Errorf("JAVAVM status is %s, expected VALID", javavm)
+}
+
+// java_policy_enabled reports whether grantee already holds an enabled
+// GRANT for the permission.
+func java_policy_enabled(ctx context.Context, db *sql.DB, grantee string, p java_permission) (bool, error) {
+	type_name := p.Type
+	if i := strings.Index(type_name, ":"); i >= 0 {
+		type_name = type_name[i+1:]
+	}
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)
+		FROM   dba_java_policy
+		WHERE  grantee = :1
+		  AND  type_name = :2
+		  AND  name = :3
+		  AND  NVL(action, '-') = NVL(:4, '-')
+		  AND  kind = 'GRANT'
+		  AND  enabled = 'ENABLED'`, grantee, type_name, p.Name, p.Action).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("query dba_java_policy failed: %w", err)
+	}
+	return n > 0, nil
+}
+
+// apply_java_permissions grants (or revokes) the permissions; owner is the
+// grantee unless an entry names another one. Enabled grants are skipped so
+// redeploying does not add duplicate policy rows.
+func apply_java_permissions(ctx context.Context, db *sql.DB, owner string, perms []java_permission) error {
+	for _, p := range perms {
+		if p.Type == "" || p.Name == "" {
+			return fmt.Errorf("java permission needs type and name: %v", p)
+		}
+		grantee := owner
+		if p.Grantee != "" {
+			var err error
+			if grantee, err = check_identifier("grantee", p.Grantee); err != nil {
+				return err
+			}
+		}
+
+		enabled, err := java_policy_enabled(ctx, db, grantee, p)
+		if err != nil {
+			return err
+		}
+		proc := "GRANT_PERMISSION"
+		if p.Revoke {
+			proc = "REVOKE_PERMISSION"
+		}
+		if enabled != p.Revoke {
+			fmt.Printf("ℹ️ java permission %s for %s already %s\n", p, grantee,
+				map[bool]string{false: "granted", true: "revoked"}[p.Revoke])
+			continue
+		}
+		if _, err := db.ExecContext(ctx, "BEGIN DBMS_JAVA."+proc+"(:1, :2, :3, :4); END;",
+			grantee, p.Type, p.Name, p.Action); err != nil {
+			return fmt.Errorf("%s %s for %s failed: %w", strings.ToLower(proc), p, grantee, err)
+		}
+		fmt.Printf("🔐 %s %s -> %s\n", strings.ToLower(proc), p, grantee)
+	}
+	return nil
+}
+
+// print_java_policy lists DBA_JAVA_POLICY for a grantee.
+func print_java_policy(ctx context.Context, db *sql.DB, grantee string) error {
+	rows, err := db.QueryContext(ctx, `
+		SELECT seq, kind, type_schema, type_name, name, NVL(action, '-'), enabled
+		FROM   dba_java_policy
+		WHERE  grantee = :1
+		ORDER BY seq`, grantee)
+	if err != nil {
+		return fmt.Errorf("query dba_java_policy failed: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Printf("%-6s %-9s %-45s %-35s %-20s %s\n", "SEQ", "KIND", "TYPE", "NAME", "ACTION", "ENABLED")
+	n := 0
+	for rows.Next() {
+		var seq int
+		var kind, schema, type_name, name, action, enabled string
+		if err := rows.Scan(&seq, &kind, &schema, &type_name, &name, &action, &enabled); err != nil {
+			return err
+		}
+		fmt.Printf("%-6d %-9s %-45s %-35s %-20s %s\n", seq, kind, schema+":"+type_name, name, action, enabled)
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	fmt.Printf("📊 %d policy row(s) for %s\n", n, grantee)
+	return nil
+}
+
+func run_java_check(args []string) error {
+	fs := flag.NewFlagSet("java check", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	fs.Parse(args)
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if err := check_javavm(ctx, db); err != nil {
+		return err
+	}
+	fmt.Println("✅ JAVAVM is ready")
+	return nil
+}
+
+func run_java_policy(args []string) error {
+	fs := flag.NewFlagSet("java policy", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "grantee to list DBA_JAVA_POLICY for")
+	fs.Parse(args)
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return print_java_policy(ctx, db, target)
+}
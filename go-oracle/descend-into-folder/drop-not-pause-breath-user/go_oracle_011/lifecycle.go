@@ -0,0 +1,330 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"flag"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+)
+
+// default_account_pattern is the -pattern of the read-only reports. Patterns
+// are matched with ESCAPE '\', so "\_" is a literal "_".
+const default_account_pattern = `USER\_SLASH\_SCHEMA\_%`
+
+// account_flags selects the accounts a lifecycle command acts on. There is
+// no default: a command that changes accounts needs -user or -pattern.
+type account_flags struct {
+	user    string
+	pattern string
+	dry_run bool
+}
+
+func add_account_flags(fs *flag.FlagSet) *account_flags {
+	af := &account_flags{}
+	fs.StringVar(&af.user, "user", "", "single account name (overrides -pattern)")
+	fs.StringVar(&af.pattern, "pattern", "", `LIKE pattern on DBA_USERS.USERNAME, e.g. USER\_SLASH\_SCHEMA\_% ("\_" is a literal "_")`)
+	fs.BoolVar(&af.dry_run, "dry-run", false, "print the statements without executing them")
+	return af
+}
+
+func (af *account_flags) check() error {
+	if strings.TrimSpace(af.user) == "" && strings.TrimSpace(af.pattern) == "" {
+		return fmt.Errorf("-user or -pattern is required")
+	}
+	return nil
+}
+
+// resolve_accounts returns the matching non Oracle-maintained accounts in
+// the current container, ordered by name.
+func resolve_accounts(ctx context.Context, db *sql.DB, af *account_flags) ([]string, error) {
+	q := `
+		SELECT username
+		FROM   dba_users
+		WHERE  username LIKE :1 ESCAPE '\'
+		  AND  oracle_maintained = 'N'
+		ORDER BY username`
+	arg := strings.ToUpper(af.pattern)
+	if af.user != "" {
+		q = `
+		SELECT username
+		FROM   dba_users
+		WHERE  username = :1
+		  AND  oracle_maintained = 'N'`
+		arg = strings.ToUpper(af.user)
+	}
+
+	rows, err := db.QueryContext(ctx, q, arg)
+	if err != nil {
+		return nil, fmt.Errorf("list accounts failed: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no accounts match %q", arg)
+	}
+	return names, nil
+}
+
+func run_account_lock(args []string) error {
+	return run_account_action("lock", "ACCOUNT LOCK", args)
+}
+
+func run_account_unlock(args []string) error {
+	return run_account_action("unlock", "ACCOUNT UNLOCK", args)
+}
+
+func run_account_expire(args []string) error {
+	return run_account_action("expire", "PASSWORD EXPIRE", args)
+}
+
+// run_account_action applies one ALTER USER clause to every selected account.
+func run_account_action(name, clause string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	af := add_account_flags(fs)
+	fs.Parse(args)
+	if err := af.check(); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	names, err := resolve_accounts(ctx, db, af)
+	if err != nil {
+		return err
+	}
+
+	ok_count := 0
+	fail_count := 0
+	for _, user := range names {
+		stmt := fmt.Sprintf("ALTER USER %s %s", quote_identifier(user), clause)
+		if af.dry_run {
+			fmt.Printf("🔎 [dry-run] %s\n", stmt)
+			continue
+		}
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			fmt.Printf("❌ %s %-40s (error: %v)\n", name, user, err)
+			fail_count++
+			continue
+		}
+		fmt.Printf("✅ %s %s\n", name, user)
+		ok_count++
+	}
+	if af.dry_run {
+		fmt.Printf("📊 %d account(s) would be changed\n", len(names))
+		return nil
+	}
+	fmt.Printf("📊 %s OK=%d, failed=%d\n", name, ok_count, fail_count)
+	if fail_count > 0 {
+		return fmt.Errorf("%d account(s) failed", fail_count)
+	}
+	return nil
+}
+
+// run_account_rotate sets a new password on every selected account.
+func run_account_rotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	af := add_account_flags(fs)
+	secret := fs.String("secret", "generate", "secret source: env:VAR, file:PATH or generate (random per account)")
+	length := fs.Int("length", 20, "length of generated passwords")
+	fs.Parse(args)
+	if err := af.check(); err != nil {
+		return err
+	}
+
+	next_password, err := secret_source(*secret, *length)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	names, err := resolve_accounts(ctx, db, af)
+	if err != nil {
+		return err
+	}
+
+	ok_count := 0
+	fail_count := 0
+	for _, user := range names {
+		if af.dry_run {
+			fmt.Printf("🔎 [dry-run] ALTER USER %s IDENTIFIED BY \"****\"\n", quote_identifier(user))
+			continue
+		}
+		pw, err := next_password()
+		if err != nil {
+			return err
+		}
+		stmt := fmt.Sprintf(`ALTER USER %s IDENTIFIED BY "%s"`, quote_identifier(user), pw)
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			fmt.Printf("❌ rotate %-40s (error: %v)\n", user, err)
+			fail_count++
+			continue
+		}
+		if *secret == "generate" {
+			fmt.Printf("🔑 rotate %s (password: %s)\n", user, pw)
+		} else {
+			fmt.Printf("🔑 rotate %s (password from %s)\n", user, *secret)
+		}
+		ok_count++
+	}
+	if af.dry_run {
+		fmt.Printf("📊 %d account(s) would be changed\n", len(names))
+		return nil
+	}
+	fmt.Printf("📊 rotate OK=%d, failed=%d\n", ok_count, fail_count)
+	if fail_count > 0 {
+		return fmt.Errorf("%d account(s) failed", fail_count)
+	}
+	return nil
+}
+
+// secret_source turns a -secret specification into a password supplier.
+// env: and file: return the same secret for every account; generate
+// returns a fresh random password on each call.
+func secret_source(spec string, length int) (func() (string, error), error) {
+	fixed := func(pw string) (func() (string, error), error) {
+		pw = strings.TrimSpace(pw)
+		if pw == "" {
+			return nil, fmt.Errorf("secret %s is empty", spec)
+		}
+		if strings.Contains(pw, `"`) {
+			return nil, fmt.Errorf("secret %s contains a double quote, which Oracle passwords cannot", spec)
+		}
+		return func() (string, error) { return pw, nil }, nil
+	}
+
+	switch {
+	case strings.HasPrefix(spec, "env:"):
+		return fixed(os.Getenv(strings.TrimPrefix(spec, "env:")))
+	case strings.HasPrefix(spec, "file:"):
+		data, err := os.ReadFile(strings.TrimPrefix(spec, "file:"))
+		if err != nil {
+			return nil, fmt.Errorf("read secret file: %w", err)
+		}
+		return fixed(string(data))
+	case spec == "generate":
+		if length < 8 {
+			return nil, fmt.Errorf("generated password length %d is too short", length)
+		}
+		return func() (string, error) { return generate_password(length) }, nil
+	}
+	return nil, fmt.Errorf("unknown secret source %q (want env:VAR, file:PATH or generate)", spec)
+}
+
+// generate_password returns a random password that starts with a letter and
+// only uses characters that need no escaping inside a quoted password.
+func generate_password(length int) (string, error) {
+	const letters = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	const alphabet = letters + "23456789_#"
+
+	var sb strings.Builder
+	for i := 0; i < length; i++ {
+		set := alphabet
+		if i == 0 {
+			set = letters
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(set))))
+		if err != nil {
+			return "", fmt.Errorf("generate password: %w", err)
+		}
+		sb.WriteByte(set[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// run_account_status lists account status, lock and expiry dates either for
+// the selected container or, with -all-pdbs, for every container from root.
+func run_account_status(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	pattern := fs.String("pattern", default_account_pattern, `LIKE pattern on USERNAME ("\_" is a literal "_")`)
+	all_pdbs := fs.Bool("all-pdbs", false, "query CDB_USERS from CDB$ROOT across all open PDBs")
+	fs.Parse(args)
+
+	if *all_pdbs {
+		cf.container = ""
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	q := `
+		SELECT SYS_CONTEXT('USERENV','CON_NAME'), username, account_status,
+		       lock_date, expiry_date, profile
+		FROM   dba_users
+		WHERE  username LIKE :1 ESCAPE '\'
+		ORDER BY username`
+	if *all_pdbs {
+		q = `
+		SELECT c.name, u.username, u.account_status,
+		       u.lock_date, u.expiry_date, u.profile
+		FROM   cdb_users u
+		JOIN   v$containers c ON c.con_id = u.con_id
+		WHERE  u.username LIKE :1 ESCAPE '\'
+		ORDER BY c.name, u.username`
+	}
+
+	rows, err := db.QueryContext(ctx, q, strings.ToUpper(*pattern))
+	if err != nil {
+		return fmt.Errorf("account status query failed: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Printf("%-30s %-40s %-20s %-19s %-19s %s\n",
+		"CONTAINER", "USERNAME", "ACCOUNT_STATUS", "LOCK_DATE", "EXPIRY_DATE", "PROFILE")
+	count := 0
+	for rows.Next() {
+		var con, user, status, profile string
+		var lock_date, expiry_date sql.NullTime
+		if err := rows.Scan(&con, &user, &status, &lock_date, &expiry_date, &profile); err != nil {
+			return err
+		}
+		fmt.Printf("%-30s %-40s %-20s %-19s %-19s %s\n",
+			con, user, status, format_null_time(lock_date), format_null_time(expiry_date), profile)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	fmt.Printf("📊 %d account(s)\n", count)
+	return nil
+}
+
+func format_null_time(t sql.NullTime) string {
+	if !t.Valid {
+		return "-"
+	}
+	return t.Time.Format(time.DateTime)
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+	_ "github.com/godror/godror"
+)
+
+const (
+	MAX_IDENTIFIER_LEN    = 128 // Oracle 12.2+
+	LEGACY_IDENTIFIER_LEN = 30  // pre-12.2 fallback
+)
+
+const (
+	default_config_path      = "sysdba.yaml"
+	default_target_container = "pdb_2025_008_004_010_033_019" // change if needed
+)
+
+type oracle_config struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	Host         string `yaml:"host"`
+	Port         int    `yaml:"port"`
+	Service_name string `yaml:"service_name"`
+	Proxy_target string `yaml:"proxy_target"` // connect as username[proxy_target]
+	Admin_role   string `yaml:"admin_role"`   // e.g. SYSDBA; empty for a normal session
+
+	Vars map[string]string `yaml:"vars"` // {{.Vars.name}} in .tmpl files
+}
+
+type config struct {
+	Oracle_connection   oracle_config            `yaml:"oracle_connection"`
+	Connection_profiles map[string]oracle_config `yaml:"connection_profiles"`
+}
+
+func load_config(path string) (*config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// profile returns the named connection profile. Host, port and service name
+// fall back to oracle_connection when the profile leaves them empty.
+func (cfg *config) profile(name string) (oracle_config, error) {
+	oc, ok := cfg.Connection_profiles[name]
+	if !ok {
+		return oracle_config{}, fmt.Errorf("connection profile %q not found", name)
+	}
+	if oc.Host == "" {
+		oc.Host = cfg.Oracle_connection.Host
+	}
+	if oc.Port == 0 {
+		oc.Port = cfg.Oracle_connection.Port
+	}
+	if oc.Service_name == "" {
+		oc.Service_name = cfg.Oracle_connection.Service_name
+	}
+	return oc, nil
+}
+
+// command is one subcommand of the tool. run receives the arguments that
+// follow the command name.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands = []command{
+	{"provision", "create a timestamped user, grant roles/privileges, deploy demo code (default)", run_provision},
+	{"lock", "lock accounts matching -user or -pattern", run_account_lock},
+	{"unlock", "unlock accounts matching -user or -pattern", run_account_unlock},
+	{"expire", "expire passwords of accounts matching -user or -pattern", run_account_expire},
+	{"rotate", "set new passwords from a secret source (-secret env:VAR|file:PATH|generate)", run_account_rotate},
+	{"status", "list account status, lock date and expiry date from DBA_USERS", run_account_status},
+	{"profiles", "apply profiles.yaml (CREATE/ALTER PROFILE) or report users by profile", run_profiles},
+	{"tablespace", "create, resize, add-datafile, autoextend, drop or list tablespaces", run_tablespace},
+	{"deploy", "compile .sql/.pks/.pkb/.java/.js files of a directory into -owner", run_deploy},
+	{"migrate", "versioned schema migrations: up, down, status, repair", run_migrate},
+	{"export-ddl", "write the DDL of a schema (DBMS_METADATA) to one file per object under tables/, packages/, java/, ...", run_export_ddl},
+	{"schema", "compare two schemas (users, containers or connection profiles): diff with a sync script", run_schema},
+	{"java", "Java stored procedures: check, policy, load, callspecs, list, drop, resolve", run_java},
+	{"mle", "MLE JavaScript modules: check, callspecs", run_mle},
+	{"recompile", "recompile INVALID objects of -owner or -all in dependency order (or UTL_RECOMP)", run_recompile},
+	{"errors", "show ALL_ERRORS of a schema with source context; -report writes JSON or SARIF", run_errors},
+	{"script", "run, parse or check SQL*Plus scripts (;, /, &var, DEFINE, PROMPT, WHENEVER)", run_script_group},
+	{"test", "run the YAML unit tests of db/tests (or the given files) and roll them back; TAP/JUnit output", run_test},
+	{"call", "call a procedure or function with -arg name=value, typed from ALL_ARGUMENTS; prints OUT values and cursors", run_call},
+	{"codegen", "generate typed Go wrappers for PL/SQL and table CRUD (golden-file checked)", run_codegen},
+	{"template", "render or check .tmpl DDL files (text/template with owner, container, CDB, -var)", run_template},
+	{"verify-hash", "compare hash_of_input with Go's SHA-256 over generated and file inputs; shrink mismatches", run_verify_hash},
+	{"whoami", "show SESSION_USER, PROXY_USER and container for sysdba or a -profile", run_whoami},
+}
+
+func main() {
+	// no arguments keeps the original behaviour: provision a fresh user
+	if len(os.Args) < 2 {
+		if err := run_provision(nil); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		return
+	}
+
+	name, args := os.Args[1], os.Args[2:]
+	if name == "help" || name == "-h" || name == "--help" {
+		print_usage()
+		return
+	}
+	for _, c := range commands {
+		if c.name == name {
+			if err := c.run(args); err != nil {
+				log.Fatalf("❌ %s: %v", name, err)
+			}
+			return
+		}
+	}
+	print_usage()
+	log.Fatalf("❌ unknown command %q", name)
+}
+
+func print_usage() {
+	fmt.Fprintf(os.Stderr, "usage: %s <command> [flags]\n\ncommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nrun \"%s <command> -h\" for the flags of a command\n", os.Args[0])
+}
+
+// run_group dispatches "<group> <sub> [flags]" to one of subs.
+func run_group(group string, subs []command, args []string) error {
+	if len(args) > 0 {
+		for _, c := range subs {
+			if c.name == args[0] {
+				return c.run(args[1:])
+			}
+		}
+	}
+	fmt.Fprintf(os.Stderr, "usage: %s %s <subcommand> [flags]\n\nsubcommands:\n", os.Args[0], group)
+	for _, c := range subs {
+		fmt.Fprintf(os.Stderr, "  %-12s %s\n", c.name, c.summary)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("missing subcommand")
+	}
+	return fmt.Errorf("unknown subcommand %q", args[0])
+}
+
+// parse_interspersed parses flags that may appear before or after the
+// positional arguments ("deploy db -owner X") and returns the positionals.
+func parse_interspersed(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// ---- connection helpers ----
+
+// conn_flags holds the connection flags shared by every command.
+type conn_flags struct {
+	config_path string
+	container   string
+}
+
+func add_conn_flags(fs *flag.FlagSet) *conn_flags {
+	cf := &conn_flags{}
+	fs.StringVar(&cf.config_path, "config", default_config_path, "YAML file with the SYSDBA oracle_connection")
+	fs.StringVar(&cf.container, "container", default_target_container, "PDB to switch to (CDB$ROOT or \"\" stays in root)")
+	return cf
+}
+
+// open connects as SYSDBA using the config file and switches to the
+// requested container. The pool is limited to one connection so that
+// session state (container, current_schema) applies to every statement.
+func (cf *conn_flags) open(ctx context.Context) (*sql.DB, error) {
+	cfg, err := load_config(cf.config_path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	db, err := open_sysdba(cfg.Oracle_connection)
+	if err != nil {
+		return nil, err
+	}
+
+	var cdb string
+	if err := db.QueryRowContext(ctx, "SELECT name FROM v$database").Scan(&cdb); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("query failed (v$database): %w", err)
+	}
+	fmt.Printf("✅ CDB: %s\n", cdb)
+
+	if cf.container != "" && !strings.EqualFold(cf.container, "CDB$ROOT") {
+		if err := switch_container(ctx, db, cf.container); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func open_sysdba(oc oracle_config) (*sql.DB, error) {
+	oc.Proxy_target = ""
+	oc.Admin_role = "SYSDBA"
+	return open_connection(oc)
+}
+
+// open_connection opens a session described by a connection profile. With
+// proxy_target set, godror's "proxy[target]" user form opens a proxy session.
+func open_connection(oc oracle_config) (*sql.DB, error) {
+	user := oc.Username
+	if oc.Proxy_target != "" {
+		user = fmt.Sprintf("%s[%s]", oc.Username, oc.Proxy_target)
+	}
+	dsn := fmt.Sprintf(`user="%s" password="%s" connectString="%s:%d/%s"`,
+		user, oc.Password, oc.Host, oc.Port, oc.Service_name)
+	if oc.Admin_role != "" {
+		dsn += " adminRole=" + oc.Admin_role
+	}
+
+	db, err := sql.Open("godror", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
+
+func switch_container(ctx context.Context, db *sql.DB, container string) error {
+	if _, err := db.ExecContext(ctx, "ALTER SESSION SET CONTAINER = "+container); err != nil {
+		return fmt.Errorf("failed to alter session container to %s: %w", container, err)
+	}
+	var con string
+	if err := db.QueryRowContext(ctx, "SELECT SYS_CONTEXT('USERENV','CON_NAME') FROM dual").Scan(&con); err != nil {
+		return fmt.Errorf("could not confirm container: %w", err)
+	}
+	fmt.Printf("📦 Current container: %s\n", con)
+	return nil
+}
+
+// ---- helpers (snake_case) ----
+
+func sanitize_oracle_identifier(s string) string {
+	s = strings.ToUpper(s)
+	re := regexp.MustCompile(`[^A-Z0-9_\$#]`)
+	s = re.ReplaceAllString(s, "_")
+	if len(s) > 0 && (s[0] < 'A' || s[0] > 'Z') {
+		s = "U_" + s
+	}
+	return s
+}
+
+func truncate_identifier(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max]
+}
+
+func is_identifier_too_long(err error) bool {
+	return strings.Contains(strings.ToUpper(err.Error()), "ORA-00972")
+}
+
+var simple_identifier_re = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]*$`)
+
+// check_identifier upper-cases a user-supplied name and rejects anything
+// that would need quoting, so it can be spliced into DDL as is.
+func check_identifier(kind, s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if !simple_identifier_re.MatchString(s) || len(s) > MAX_IDENTIFIER_LEN {
+		return "", fmt.Errorf("invalid %s name %q", kind, s)
+	}
+	return strings.ToUpper(s), nil
+}
+
+// quote_literal returns s as a single-quoted SQL string literal.
+func quote_literal(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// quote_identifier wraps a name taken from the data dictionary in double
+// quotes so it is used exactly as stored.
+func quote_identifier(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
@@ -0,0 +1,528 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// migration_history_table is created in the target schema and owned by
+// this tool; nothing else should write to it.
+const migration_history_table = "GO_ORACLE_SCHEMA_HISTORY"
+
+const migration_history_ddl = `
+CREATE TABLE %s.` + migration_history_table + ` (
+  version      NUMBER(10)     NOT NULL,
+  description  VARCHAR2(200)  NOT NULL,
+  script       VARCHAR2(400)  NOT NULL,
+  checksum     VARCHAR2(64)   NOT NULL,
+  applied_by   VARCHAR2(128)  NOT NULL,
+  applied_at   TIMESTAMP WITH TIME ZONE DEFAULT SYSTIMESTAMP NOT NULL,
+  execution_ms NUMBER(12)     NOT NULL,
+  success      CHAR(1)        NOT NULL CHECK (success IN ('Y', 'N')),
+  CONSTRAINT go_oracle_schema_history_pk PRIMARY KEY (version)
+)`
+
+// migration file names: 0001_create_orders.up.sql / 0001_create_orders.down.sql
+var migration_file_re = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+type migration struct {
+	version     int
+	description string
+	up_path     string
+	down_path   string
+	checksum    string // of the up script
+}
+
+type applied_migration struct {
+	version      int
+	description  string
+	script       string
+	checksum     string
+	applied_by   string
+	applied_at   time.Time
+	execution_ms int64
+	success      bool
+}
+
+// migration_checksum is the SHA-256 of a script with line endings
+// normalized, so a checkout with CRLF does not count as an edit.
+func migration_checksum(data []byte) string {
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(string(data), "\r\n", "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// load_migrations reads the numbered scripts of dir, ordered by version.
+// Every version needs an up script; the down script is optional.
+func load_migrations(dir string) ([]*migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	by_version := map[int]*migration{}
+	for _, e := range entries {
+		m := migration_file_re.FindStringSubmatch(e.Name())
+		if e.IsDir() || m == nil {
+			continue
+		}
+		version, err := strconv.Atoi(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: bad version: %w", e.Name(), err)
+		}
+		mg := by_version[version]
+		if mg == nil {
+			mg = &migration{version: version, description: strings.ReplaceAll(m[2], "_", " ")}
+			by_version[version] = mg
+		}
+		path := filepath.Join(dir, e.Name())
+		if m[3] == "up" {
+			if mg.up_path != "" {
+				return nil, fmt.Errorf("version %d has two up scripts: %s and %s", version, mg.up_path, path)
+			}
+			mg.up_path = path
+		} else {
+			mg.down_path = path
+		}
+	}
+
+	var out []*migration
+	for _, mg := range by_version {
+		if mg.up_path == "" {
+			return nil, fmt.Errorf("version %d has no up script", mg.version)
+		}
+		data, err := os.ReadFile(mg.up_path)
+		if err != nil {
+			return nil, err
+		}
+		mg.checksum = migration_checksum(data)
+		out = append(out, mg)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].version < out[j].version })
+	return out, nil
+}
+
+// history_table_exists reports whether owner has the history table.
+func history_table_exists(ctx context.Context, db *sql.DB, owner string) (bool, error) {
+	var n int
+	if err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM all_tables WHERE owner = :1 AND table_name = :2",
+		owner, migration_history_table).Scan(&n); err != nil {
+		return false, fmt.Errorf("check history table failed: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ensure_history_table creates the history table in owner if needed.
+func ensure_history_table(ctx context.Context, db *sql.DB, owner string) error {
+	if exists, err := history_table_exists(ctx, db, owner); err != nil || exists {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(migration_history_ddl, owner)); err != nil {
+		return fmt.Errorf("create history table failed: %w", err)
+	}
+	fmt.Printf("🆕 created %s.%s\n", owner, migration_history_table)
+	return nil
+}
+
+func load_applied(ctx context.Context, db *sql.DB, owner string) (map[int]*applied_migration, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT version, description, script, checksum, applied_by, applied_at, execution_ms, success
+		FROM   %s.%s
+		ORDER BY version`, owner, migration_history_table))
+	if err != nil {
+		return nil, fmt.Errorf("read history failed: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int]*applied_migration{}
+	for rows.Next() {
+		var a applied_migration
+		var success string
+		if err := rows.Scan(&a.version, &a.description, &a.script, &a.checksum,
+			&a.applied_by, &a.applied_at, &a.execution_ms, &success); err != nil {
+			return nil, err
+		}
+		a.success = success == "Y"
+		applied[a.version] = &a
+	}
+	return applied, rows.Err()
+}
+
+// migration_lock serializes runners on one schema with DBMS_LOCK. The lock
+// is held by the session, which is why the pool is limited to one connection.
+func migration_lock(ctx context.Context, db *sql.DB, owner string, timeout time.Duration) (release func(), err error) {
+	name := "GO_ORACLE_MIGRATE_" + owner
+	var result int
+	if _, err := db.ExecContext(ctx, `
+		DECLARE
+		  l_handle VARCHAR2(128);
+		BEGIN
+		  DBMS_LOCK.ALLOCATE_UNIQUE(:1, l_handle);
+		  :2 := DBMS_LOCK.REQUEST(l_handle, DBMS_LOCK.X_MODE, :3, FALSE);
+		END;`, name, sql.Out{Dest: &result}, int(timeout.Seconds())); err != nil {
+		return nil, fmt.Errorf("request migration lock failed: %w", err)
+	}
+	switch result {
+	case 0, 4: // success, already owned
+	case 1:
+		return nil, fmt.Errorf("another migration holds the lock on %s (waited %s)", owner, timeout)
+	default:
+		return nil, fmt.Errorf("DBMS_LOCK.REQUEST returned %d", result)
+	}
+	fmt.Printf("🔒 migration lock %s acquired\n", name)
+
+	return func() {
+		var rc int
+		if _, err := db.ExecContext(context.Background(), `
+			DECLARE
+			  l_handle VARCHAR2(128);
+			BEGIN
+			  DBMS_LOCK.ALLOCATE_UNIQUE(:1, l_handle);
+			  :2 := DBMS_LOCK.RELEASE(l_handle);
+			END;`, name, sql.Out{Dest: &rc}); err != nil || rc != 0 {
+			fmt.Printf("⚠️ release migration lock failed (rc=%d): %v\n", rc, err)
+			return
+		}
+		fmt.Printf("🔓 migration lock %s released\n", name)
+	}, nil
+}
+
+// run_script executes a migration script in owner with SQL*Plus rules,
+// stopping at the first error as with WHENEVER SQLERROR EXIT.
+func run_script(ctx context.Context, db *sql.DB, owner, path string, defines map[string]string) error {
+	items, lines, err := read_script(path, defines, nil)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, "ALTER SESSION SET CURRENT_SCHEMA = "+owner); err != nil {
+		return fmt.Errorf("set current_schema failed: %w", err)
+	}
+	r := &script_runner{db: db, owner: owner, file: path, lines: lines, exit_on_error: true}
+	return r.run(ctx, filepath.Base(path), items)
+}
+
+// migrate_flags are shared by the migrate subcommands.
+type migrate_flags struct {
+	owner   string
+	dir     string
+	timeout time.Duration
+	defines define_flags
+}
+
+func add_migrate_flags(fs *flag.FlagSet) *migrate_flags {
+	mf := &migrate_flags{}
+	fs.StringVar(&mf.owner, "owner", "", "schema the migrations apply to")
+	fs.StringVar(&mf.dir, "dir", "migrations", "directory with NNNN_name.up.sql / NNNN_name.down.sql scripts")
+	fs.DurationVar(&mf.timeout, "lock-timeout", 60*time.Second, "how long to wait for another runner")
+	mf.defines = add_define_flags(fs)
+	return mf
+}
+
+// migrate_session opens the connection, creates the history table and takes
+// the schema lock. The returned close function releases both.
+func migrate_session(ctx context.Context, cf *conn_flags, mf *migrate_flags) (*sql.DB, string, func(), error) {
+	owner, err := check_identifier("owner", mf.owner)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("-owner is required: %w", err)
+	}
+	db, err := cf.open(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	release, err := migration_lock(ctx, db, owner, mf.timeout)
+	if err != nil {
+		db.Close()
+		return nil, "", nil, err
+	}
+	if err := ensure_history_table(ctx, db, owner); err != nil {
+		release()
+		db.Close()
+		return nil, "", nil, err
+	}
+	return db, owner, func() { release(); db.Close() }, nil
+}
+
+func run_migrate(args []string) error {
+	return run_group("migrate", []command{
+		{"up", "apply pending migrations (optionally -to VERSION)", run_migrate_up},
+		{"down", "roll back the latest -steps applied migrations with their down scripts", run_migrate_down},
+		{"status", "show applied, pending, edited and failed migrations", run_migrate_status},
+		{"repair", "drop failed history rows and re-record checksums of edited scripts", run_migrate_repair},
+	}, args)
+}
+
+func run_migrate_up(args []string) error {
+	fs := flag.NewFlagSet("migrate up", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	mf := add_migrate_flags(fs)
+	to := fs.Int("to", 0, "stop after this version (0 = all)")
+	dry_run := fs.Bool("dry-run", false, "list the pending migrations without running them")
+	fs.Parse(args)
+
+	migrations, err := load_migrations(mf.dir)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, owner, done, err := migrate_session(ctx, cf, mf)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	applied, err := load_applied(ctx, db, owner)
+	if err != nil {
+		return err
+	}
+
+	// refuse to continue on top of a failed or edited history
+	for _, mg := range migrations {
+		a := applied[mg.version]
+		if a == nil {
+			continue
+		}
+		if !a.success {
+			return fmt.Errorf("version %d failed earlier; fix it and run \"migrate repair\"", mg.version)
+		}
+		if a.checksum != mg.checksum {
+			return fmt.Errorf("version %d was edited after it was applied (checksum %s, file %s); run \"migrate repair\" if intended",
+				mg.version, a.checksum[:12], mg.checksum[:12])
+		}
+	}
+
+	count := 0
+	for _, mg := range migrations {
+		if applied[mg.version] != nil || (*to > 0 && mg.version > *to) {
+			continue
+		}
+		if *dry_run {
+			fmt.Printf("🔎 [dry-run] would apply %04d %s\n", mg.version, mg.description)
+			count++
+			continue
+		}
+
+		fmt.Printf("▶ applying %04d %s\n", mg.version, mg.description)
+		start := time.Now()
+		run_err := run_script(ctx, db, owner, mg.up_path, mf.defines)
+		elapsed := time.Since(start).Milliseconds()
+
+		success := "Y"
+		if run_err != nil {
+			success = "N"
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(`
+			INSERT INTO %s.%s (version, description, script, checksum, applied_by, execution_ms, success)
+			VALUES (:1, :2, :3, :4, SYS_CONTEXT('USERENV','OS_USER') || ' as ' || USER, :5, :6)`,
+			owner, migration_history_table),
+			mg.version, mg.description, filepath.Base(mg.up_path), mg.checksum, elapsed, success); err != nil {
+			return fmt.Errorf("record version %d failed: %w", mg.version, err)
+		}
+		if run_err != nil {
+			fmt.Printf("❌ %04d %s (%d ms)\n", mg.version, mg.description, elapsed)
+			return fmt.Errorf("version %d failed: %w", mg.version, run_err)
+		}
+		fmt.Printf("✅ %04d %s (%d ms)\n", mg.version, mg.description, elapsed)
+		count++
+	}
+	if count == 0 {
+		fmt.Println("✅ schema is up to date")
+	} else if !*dry_run {
+		fmt.Printf("📊 applied %d migration(s)\n", count)
+	}
+	return nil
+}
+
+func run_migrate_down(args []string) error {
+	fs := flag.NewFlagSet("migrate down", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	mf := add_migrate_flags(fs)
+	steps := fs.Int("steps", 1, "number of migrations to roll back")
+	dry_run := fs.Bool("dry-run", false, "list the migrations that would be rolled back")
+	fs.Parse(args)
+	if *steps < 1 {
+		return fmt.Errorf("-steps must be at least 1, got %d", *steps)
+	}
+
+	migrations, err := load_migrations(mf.dir)
+	if err != nil {
+		return err
+	}
+	by_version := map[int]*migration{}
+	for _, mg := range migrations {
+		by_version[mg.version] = mg
+	}
+
+	ctx := context.Background()
+	db, owner, done, err := migrate_session(ctx, cf, mf)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	applied, err := load_applied(ctx, db, owner)
+	if err != nil {
+		return err
+	}
+	var versions []int
+	for v, a := range applied {
+		if a.success {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(versions)))
+	if len(versions) > *steps {
+		versions = versions[:*steps]
+	}
+	if len(versions) == 0 {
+		fmt.Println("ℹ️ nothing to roll back")
+		return nil
+	}
+
+	for _, v := range versions {
+		mg := by_version[v]
+		if mg == nil || mg.down_path == "" {
+			return fmt.Errorf("version %d has no down script", v)
+		}
+		if *dry_run {
+			fmt.Printf("🔎 [dry-run] would roll back %04d %s\n", v, mg.description)
+			continue
+		}
+		fmt.Printf("◀ rolling back %04d %s\n", v, mg.description)
+		if err := run_script(ctx, db, owner, mg.down_path, mf.defines); err != nil {
+			return fmt.Errorf("down script of version %d failed: %w", v, err)
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.%s WHERE version = :1",
+			owner, migration_history_table), v); err != nil {
+			return fmt.Errorf("remove history of version %d failed: %w", v, err)
+		}
+		fmt.Printf("✅ rolled back %04d %s\n", v, mg.description)
+	}
+	return nil
+}
+
+func run_migrate_status(args []string) error {
+	fs := flag.NewFlagSet("migrate status", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	mf := add_migrate_flags(fs)
+	fs.Parse(args)
+
+	migrations, err := load_migrations(mf.dir)
+	if err != nil {
+		return err
+	}
+
+	// status only reads: no lock, and a missing history table is reported
+	// rather than created
+	owner, err := check_identifier("owner", mf.owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	applied := map[int]*applied_migration{}
+	exists, err := history_table_exists(ctx, db, owner)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if applied, err = load_applied(ctx, db, owner); err != nil {
+			return err
+		}
+	} else {
+		fmt.Printf("ℹ️ no history table %s.%s: nothing has been applied\n", owner, migration_history_table)
+	}
+
+	fmt.Printf("%-7s %-35s %-8s %-19s %8s  %s\n", "VERSION", "DESCRIPTION", "STATE", "APPLIED_AT", "MS", "APPLIED_BY")
+	seen := map[int]bool{}
+	for _, mg := range migrations {
+		seen[mg.version] = true
+		a := applied[mg.version]
+		if a == nil {
+			fmt.Printf("%-7d %-35s %-8s\n", mg.version, mg.description, "pending")
+			continue
+		}
+		state := "applied"
+		switch {
+		case !a.success:
+			state = "FAILED"
+		case a.checksum != mg.checksum:
+			state = "EDITED"
+		}
+		fmt.Printf("%-7d %-35s %-8s %-19s %8d  %s\n", mg.version, mg.description, state,
+			a.applied_at.Format(time.DateTime), a.execution_ms, a.applied_by)
+	}
+	for v, a := range applied {
+		if !seen[v] {
+			fmt.Printf("%-7d %-35s %-8s %-19s %8d  %s\n", v, a.description, "MISSING",
+				a.applied_at.Format(time.DateTime), a.execution_ms, a.applied_by)
+		}
+	}
+	return nil
+}
+
+func run_migrate_repair(args []string) error {
+	fs := flag.NewFlagSet("migrate repair", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	mf := add_migrate_flags(fs)
+	fs.Parse(args)
+
+	migrations, err := load_migrations(mf.dir)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, owner, done, err := migrate_session(ctx, cf, mf)
+	if err != nil {
+		return err
+	}
+	defer done()
+
+	applied, err := load_applied(ctx, db, owner)
+	if err != nil {
+		return err
+	}
+
+	changed := 0
+	for _, mg := range migrations {
+		a := applied[mg.version]
+		switch {
+		case a == nil:
+			continue
+		case !a.success:
+			if _, err := db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s.%s WHERE version = :1",
+				owner, migration_history_table), mg.version); err != nil {
+				return fmt.Errorf("remove failed version %d: %w", mg.version, err)
+			}
+			fmt.Printf("🔧 removed failed entry %04d %s\n", mg.version, mg.description)
+			changed++
+		case a.checksum != mg.checksum:
+			if _, err := db.ExecContext(ctx, fmt.Sprintf("UPDATE %s.%s SET checksum = :1 WHERE version = :2",
+				owner, migration_history_table), mg.checksum, mg.version); err != nil {
+				return fmt.Errorf("update checksum of version %d: %w", mg.version, err)
+			}
+			fmt.Printf("🔧 re-recorded checksum of %04d %s\n", mg.version, mg.description)
+			changed++
+		}
+	}
+	if changed == 0 {
+		fmt.Println("✅ history needs no repair")
+	}
+	return nil
+}
@@ -0,0 +1,2 @@
+DROP TABLE hash_log PURGE
+/
@@ -0,0 +1,10 @@
+-- SQL*Plus rules: statements end with ";" or a line holding a single "/"
+CREATE TABLE hash_log (
+  id         NUMBER GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+  input_text CLOB,
+  sha256_hex VARCHAR2(64) NOT NULL,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT SYSTIMESTAMP NOT NULL
+)
+/
+CREATE INDEX hash_log_sha256_ix ON hash_log (sha256_hex)
+/
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// default_js_sql_types maps the JSDoc types of exported functions to the
+// SQL types of the generated call specs. call_spec_config.Types overrides it.
+var default_js_sql_types = map[string]string{
+	"string":     "VARCHAR2",
+	"number":     "NUMBER",
+	"bigint":     "NUMBER",
+	"boolean":    "BOOLEAN",
+	"Date":       "DATE",
+	"Uint8Array": "RAW",
+	"object":     "JSON",
+	"Object":     "JSON",
+}
+
+// mle_config is the mle section of a deploy manifest.
+type mle_config struct {
+	Optional     bool              `yaml:"optional"`     // skip .js units when the database has no MLE
+	Environments []mle_environment `yaml:"environments"` // created once the modules they import are deployed
+}
+
+type mle_environment struct {
+	Name             string            `yaml:"name"`
+	Imports          map[string]string `yaml:"imports"`          // import specifier -> module name
+	Language_options string            `yaml:"language_options"` // e.g. js.strict=true
+}
+
+// ddl returns CREATE OR REPLACE MLE ENV with the imports sorted by specifier.
+// The environment and its modules are qualified with owner (unless it is
+// ""), as the session's current schema is not necessarily owner.
+func (e mle_environment) ddl(owner string) string {
+	qualify := func(name string) string {
+		if owner == "" {
+			return quote_identifier(normalize_identifier(name))
+		}
+		return quote_identifier(owner) + "." + quote_identifier(normalize_identifier(name))
+	}
+	var imports []string
+	for spec, module := range e.Imports {
+		imports = append(imports, fmt.Sprintf("%s MODULE %s", quote_literal(spec), qualify(module)))
+	}
+	sort.Strings(imports)
+	ddl := "CREATE OR REPLACE MLE ENV " + qualify(e.Name)
+	if len(imports) > 0 {
+		ddl += " IMPORTS (" + strings.Join(imports, ", ") + ")"
+	}
+	if e.Language_options != "" {
+		ddl += " LANGUAGE OPTIONS " + quote_literal(e.Language_options)
+	}
+	return ddl
+}
+
+// mle_supported reports whether CREATE MLE MODULE can work: the MLE
+// dictionary views exist (23ai and later) and MLE_PROG_LANGUAGES is not OFF.
+// The reason is set when it cannot.
+func mle_supported(ctx context.Context, db *sql.DB) (bool, string, error) {
+	var version string
+	if err := db.QueryRowContext(ctx, "SELECT version FROM v$instance").Scan(&version); err != nil {
+		return false, "", fmt.Errorf("query v$instance failed: %w", err)
+	}
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM dba_views
+		WHERE  owner = 'SYS' AND view_name = 'DBA_MLE_MODULES'`).Scan(&n)
+	if err != nil {
+		return false, "", fmt.Errorf("query dba_views failed: %w", err)
+	}
+	if n == 0 {
+		return false, fmt.Sprintf("Oracle %s has no MLE (23ai or later is needed)", version), nil
+	}
+	var langs sql.NullString
+	err = db.QueryRowContext(ctx, "SELECT value FROM v$parameter WHERE name = 'mle_prog_languages'").Scan(&langs)
+	if err != nil && err != sql.ErrNoRows {
+		return false, "", fmt.Errorf("query v$parameter failed: %w", err)
+	}
+	if strings.EqualFold(langs.String, "OFF") {
+		return false, "MLE is disabled (MLE_PROG_LANGUAGES = OFF)", nil
+	}
+	fmt.Printf("🟨 MLE available (Oracle %s, MLE_PROG_LANGUAGES = %s)\n", version, langs.String)
+	return true, "", nil
+}
+
+// create_mle_env creates an environment and checks DBA_MLE_ENVS for it.
+func create_mle_env(ctx context.Context, db *sql.DB, owner string, e mle_environment) error {
+	if _, err := db.ExecContext(ctx, e.ddl(owner)); err != nil {
+		return fmt.Errorf("create mle env %s failed: %w", e.Name, err)
+	}
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM dba_mle_envs
+		WHERE  owner = :1 AND env_name = :2`, owner, normalize_identifier(e.Name)).Scan(&n)
+	if err != nil {
+		return fmt.Errorf("verify mle env failed: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("mle env %s.%s not found in DBA_MLE_ENVS after create", owner, normalize_identifier(e.Name))
+	}
+	fmt.Printf("✅ mle env %s.%s\n", owner, normalize_identifier(e.Name))
+	return nil
+}
+
+// mle_env_tracker creates each environment of the manifest as soon as the
+// modules it imports are deployed. Modules no unit defines are expected to
+// exist already.
+type mle_env_tracker struct {
+	pending []mle_environment
+	waiting map[string]bool // modules still to be deployed
+}
+
+func new_mle_env_tracker(cfg *mle_config, units []*deploy_unit) *mle_env_tracker {
+	t := &mle_env_tracker{waiting: map[string]bool{}}
+	if cfg != nil {
+		t.pending = cfg.Environments
+	}
+	for _, u := range units {
+		for _, obj := range u.objects {
+			if obj.obj_type == "MLE MODULE" {
+				t.waiting[obj.name] = true
+			}
+		}
+	}
+	return t
+}
+
+// deployed marks the modules of u as done and creates the environments
+// that became ready.
+func (t *mle_env_tracker) deployed(ctx context.Context, db *sql.DB, owner string, u *deploy_unit) error {
+	if u != nil {
+		for _, obj := range u.objects {
+			delete(t.waiting, obj.name)
+		}
+	}
+	var still []mle_environment
+	for _, e := range t.pending {
+		ready := true
+		for _, module := range e.Imports {
+			if t.waiting[normalize_identifier(module)] {
+				ready = false
+				break
+			}
+		}
+		if !ready {
+			still = append(still, e)
+			continue
+		}
+		if err := create_mle_env(ctx, db, owner, e); err != nil {
+			return err
+		}
+	}
+	t.pending = still
+	return nil
+}
+
+// skip_mle_units drops the .js units (and the environments) when MLE is not
+// supported and the manifest marks it optional; otherwise that is an error.
+func skip_mle_units(ctx context.Context, db *sql.DB, units []*deploy_unit, cfg *mle_config) ([]*deploy_unit, *mle_config, error) {
+	if !needs_mle(units) && (cfg == nil || len(cfg.Environments) == 0) {
+		return units, cfg, nil
+	}
+	ok, reason, err := mle_supported(ctx, db)
+	if err != nil || ok {
+		return units, cfg, err
+	}
+	if cfg == nil || !cfg.Optional {
+		return nil, nil, fmt.Errorf("%s (set mle.optional in the manifest or use -skip-unsupported-mle to skip the .js units)", reason)
+	}
+	var kept []*deploy_unit
+	for _, u := range units {
+		if is_js_unit(u) {
+			fmt.Printf("⚠️ skipping %s: %s\n", u.path, reason)
+			continue
+		}
+		kept = append(kept, u)
+	}
+	for _, e := range cfg.Environments {
+		fmt.Printf("⚠️ skipping mle env %s: %s\n", e.Name, reason)
+	}
+	return kept, nil, nil
+}
+
+func is_js_unit(u *deploy_unit) bool {
+	return unit_ext(u.path) == ".js"
+}
+
+// needs_mle reports whether any unit creates an MLE module.
+func needs_mle(units []*deploy_unit) bool {
+	return slices.ContainsFunc(units, is_js_unit)
+}
+
+// js_function is an exported function of a module with the types of its
+// JSDoc comment ("" where there is none). returns is "" for procedures.
+type js_function struct {
+	name        string
+	param_names []string
+	param_types []string
+	returns     string
+	async       bool
+}
+
+// signature is the SIGNATURE clause string, e.g. 'upper(string)'.
+func (f js_function) signature() string {
+	return fmt.Sprintf("%s(%s)", f.name, strings.Join(f.param_types, ", "))
+}
+
+var (
+	js_comment_re     = regexp.MustCompile(`(?s)/\*.*?\*/|//[^\n]*`)
+	js_export_func_re = regexp.MustCompile(`\bexport\s+(async\s+)?function\s*(\*)?\s*([A-Za-z_$][\w$]*)\s*\(([^)]*)\)`)
+	js_export_list_re = regexp.MustCompile(`\bexport\s*\{([^}]*)\}`)
+	js_param_tag_re   = regexp.MustCompile(`@param\s+\{([^}]+)\}\s+\[?([A-Za-z_$][\w$]*)`)
+	js_returns_tag_re = regexp.MustCompile(`@returns?\s+\{([^}]+)\}`)
+)
+
+// parse_js_exports returns the exported functions of a module: "export
+// function f" and functions named in "export { f, g as h }", typed by the
+// JSDoc comment right before their declaration.
+func parse_js_exports(src string) ([]js_function, error) {
+	var funcs []js_function
+	for _, m := range js_export_func_re.FindAllStringSubmatchIndex(src, -1) {
+		if m[4] >= 0 {
+			continue // generators cannot be called from SQL
+		}
+		f, err := js_declared_function(src, m, src[m[6]:m[7]])
+		if err != nil {
+			return nil, err
+		}
+		funcs = append(funcs, f)
+	}
+
+	code := js_comment_re.ReplaceAllString(src, "")
+	for _, m := range js_export_list_re.FindAllStringSubmatch(code, -1) {
+		for _, entry := range strings.Split(m[1], ",") {
+			words := strings.Fields(entry)
+			if len(words) == 0 {
+				continue
+			}
+			local, exported := words[0], words[0]
+			if len(words) == 3 && words[1] == "as" {
+				exported = words[2]
+			}
+			decl_re := regexp.MustCompile(`\b(async\s+)?function\s*(\*)?\s*(` + regexp.QuoteMeta(local) + `)\s*\(([^)]*)\)`)
+			dm := decl_re.FindStringSubmatchIndex(src)
+			if dm == nil || dm[4] >= 0 {
+				continue // not a function declaration (const, class, ...)
+			}
+			f, err := js_declared_function(src, dm, exported)
+			if err != nil {
+				return nil, err
+			}
+			funcs = append(funcs, f)
+		}
+	}
+	return funcs, nil
+}
+
+// js_declared_function builds a js_function from a declaration match with
+// the groups (async)(*)(name)(params).
+func js_declared_function(src string, m []int, exported string) (js_function, error) {
+	f := js_function{name: exported, async: m[2] >= 0}
+	doc := js_doc_before(src[:m[0]])
+	types := map[string]string{}
+	for _, pm := range js_param_tag_re.FindAllStringSubmatch(doc, -1) {
+		types[pm[2]] = strings.TrimSpace(pm[1])
+	}
+	if rm := js_returns_tag_re.FindStringSubmatch(doc); rm != nil {
+		if t := strings.TrimSpace(rm[1]); t != "void" && t != "undefined" {
+			f.returns = t
+		}
+	}
+
+	params := strings.TrimSpace(js_comment_re.ReplaceAllString(src[m[8]:m[9]], ""))
+	if params == "" {
+		return f, nil
+	}
+	for _, p := range strings.Split(params, ",") {
+		name, _, _ := strings.Cut(p, "=") // default values
+		name = strings.TrimSpace(name)
+		if strings.HasPrefix(name, "...") || strings.ContainsAny(name, "{[") {
+			return f, fmt.Errorf("%s: rest and destructured parameters are not supported in call specs", exported)
+		}
+		f.param_names = append(f.param_names, name)
+		f.param_types = append(f.param_types, types[name])
+	}
+	return f, nil
+}
+
+// js_doc_before returns the body of the /** ... */ comment that ends the
+// text before a declaration, or "".
+func js_doc_before(before string) string {
+	before = strings.TrimRight(before, " \t\r\n")
+	body, ok := strings.CutSuffix(before, "*/")
+	if !ok {
+		return ""
+	}
+	start := strings.LastIndex(body, "/**")
+	if start < 0 || strings.Contains(body[start:], "*/") {
+		return ""
+	}
+	return body[start+3:]
+}
+
+// generate_mle_call_specs builds one FUNCTION (or PROCEDURE when there is no
+// @returns) per exported function of module. Async functions and functions
+// whose parameter types are unknown or unmapped are skipped with a warning.
+func generate_mle_call_specs(cfg *call_spec_config, module string, funcs []js_function) []call_spec {
+	wanted := map[string]bool{}
+	for _, name := range cfg.Methods {
+		wanted[name] = true
+	}
+	sql_type := func(t string) (string, bool) {
+		if st, ok := cfg.Types[t]; ok {
+			return st, true
+		}
+		st, ok := default_js_sql_types[t]
+		return st, ok
+	}
+
+	env := ""
+	if cfg.Env != "" {
+		env = " ENV " + quote_identifier(normalize_identifier(cfg.Env))
+	}
+	var specs []call_spec
+	for _, f := range funcs {
+		if len(wanted) > 0 && !wanted[f.name] {
+			continue
+		}
+		if f.async {
+			fmt.Printf("⚠️ %s.%s is async; skipping (call specs need a synchronous function)\n", module, f.name)
+			continue
+		}
+
+		name := strings.ToUpper(f.name + cfg.Suffix)
+		var params, args []string
+		unmapped := ""
+		for i, jt := range f.param_types {
+			st, ok := sql_type(jt)
+			if !ok {
+				unmapped = fmt.Sprintf("parameter %s {%s}", f.param_names[i], jt)
+				break
+			}
+			params = append(params, fmt.Sprintf("p_%s %s", strings.ToLower(f.param_names[i]), st))
+			args = append(args, smoke_test_values[st])
+		}
+		ret := ""
+		if unmapped == "" && f.returns != "" {
+			var ok bool
+			if ret, ok = sql_type(f.returns); !ok {
+				unmapped = fmt.Sprintf("return type {%s}", f.returns)
+			}
+		}
+		if unmapped != "" {
+			fmt.Printf("⚠️ %s.%s: no SQL type for %s; skipping (add a JSDoc type or types)\n", module, f.name, unmapped)
+			continue
+		}
+
+		param_list := ""
+		if len(params) > 0 {
+			param_list = "(" + strings.Join(params, ", ") + ")"
+		}
+		clause := fmt.Sprintf("AS MLE MODULE %s%s\nSIGNATURE %s;", quote_identifier(module), env, quote_literal(f.signature()))
+		cs := call_spec{name: name}
+		if f.returns == "" {
+			cs.ddl = fmt.Sprintf("CREATE OR REPLACE PROCEDURE %s%s\n%s", name, param_list, clause)
+		} else {
+			cs.ddl = fmt.Sprintf("CREATE OR REPLACE FUNCTION %s%s\n  RETURN %s\n%s", name, param_list, ret, clause)
+			if !cfg.No_test && !slices.Contains(args, "") {
+				call := name
+				if len(args) > 0 {
+					call += "(" + strings.Join(args, ", ") + ")"
+				}
+				cs.test_sql = fmt.Sprintf("SELECT %s FROM dual", call)
+			}
+		}
+		specs = append(specs, cs)
+	}
+	sort.SliceStable(specs, func(i, j int) bool { return specs[i].name < specs[j].name })
+	return specs
+}
+
+// deploy_mle_call_specs generates, compiles and smoke-tests the call specs
+// of a module that has just been created in owner.
+func deploy_mle_call_specs(ctx context.Context, db *sql.DB, owner, module, src string, cfg *call_spec_config) error {
+	if cfg.From_db {
+		return fmt.Errorf("from_db is only supported for Java")
+	}
+	funcs, err := parse_js_exports(src)
+	if err != nil {
+		return err
+	}
+	specs := generate_mle_call_specs(cfg, module, funcs)
+	if len(specs) == 0 {
+		return fmt.Errorf("no call specs generated for mle module %s", module)
+	}
+	for _, cs := range specs {
+		fmt.Printf("🔗 call spec %s -> mle module %s\n", cs.name, module)
+		if _, err := deploy_ddl(ctx, db, owner, cs.ddl, nil); err != nil {
+			return err
+		}
+		if cs.test_sql != "" {
+			if err := smoke_test(ctx, db, cs.name, cs.test_sql); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func run_mle(args []string) error {
+	return run_group("mle", []command{
+		{"check", "report whether the database supports MLE JavaScript modules", run_mle_check},
+		{"callspecs", "print the PL/SQL call specs for the exported functions of a .js module", run_mle_callspecs},
+	}, args)
+}
+
+func run_mle_check(args []string) error {
+	fs := flag.NewFlagSet("mle check", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	fs.Parse(args)
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	ok, reason, err := mle_supported(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("%s", reason)
+	}
+	fmt.Println("✅ MLE is ready")
+	return nil
+}
+
+func run_mle_callspecs(args []string) error {
+	fs := flag.NewFlagSet("mle callspecs", flag.ExitOnError)
+	spec := fs.String("spec", "", "YAML with suffix, methods, types, env, no_test")
+	suffix := fs.String("suffix", "", "appended to the function names (overrides the config)")
+	env := fs.String("env", "", "MLE environment of the call specs (overrides the config)")
+	positional := parse_interspersed(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: mle callspecs [flags] <file.js>")
+	}
+
+	data, err := os.ReadFile(positional[0])
+	if err != nil {
+		return err
+	}
+	cfg, err := load_call_spec_config(*spec)
+	if err != nil {
+		return fmt.Errorf("could not load call spec config: %w", err)
+	}
+	if *suffix != "" {
+		cfg.Suffix = *suffix
+	}
+	if *env != "" {
+		cfg.Env = *env
+	}
+	funcs, err := parse_js_exports(string(data))
+	if err != nil {
+		return fmt.Errorf("%s: %w", positional[0], err)
+	}
+	module := normalize_identifier(base_name(positional[0]))
+	for _, cs := range generate_mle_call_specs(cfg, module, funcs) {
+		fmt.Printf("%s\n/\n", cs.ddl)
+		if cs.test_sql != "" {
+			fmt.Printf("-- test: %s\n", cs.test_sql)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// db_object identifies a schema object the way ALL_OBJECTS and ALL_ERRORS
+// do: owner, OBJECT_TYPE (e.g. "PACKAGE BODY") and name as stored.
+type db_object struct {
+	owner    string
+	obj_type string
+	name     string
+}
+
+func (o db_object) String() string {
+	if o.owner == "" {
+		return o.obj_type + " " + o.name
+	}
+	return fmt.Sprintf("%s %s.%s", o.obj_type, o.owner, o.name)
+}
+
+// label is the object type as used in messages, e.g. "Package body".
+func (o db_object) label() string {
+	t := strings.ToLower(o.obj_type)
+	return strings.ToUpper(t[:1]) + t[1:]
+}
+
+const ident_pattern = `("[^"]+"|[A-Za-z][A-Za-z0-9_$#]*)`
+
+var ddl_head_re = regexp.MustCompile(`(?is)^CREATE\s+` +
+	`(?:OR\s+REPLACE\s+)?` +
+	`(?:AND\s+(?:RESOLVE|COMPILE)\s+)?` +
+	`(?:(?:NO)?FORCE\s+)?` +
+	`(?:(?:NON)?EDITIONABLE\s+|EDITIONING\s+)*` +
+	`(PUBLIC\s+)?` +
+	`(PACKAGE\s+BODY|TYPE\s+BODY|PACKAGE|TYPE|FUNCTION|PROCEDURE|TRIGGER|MATERIALIZED\s+VIEW|VIEW|SYNONYM|LIBRARY|` +
+	`JAVA\s+SOURCE|JAVA\s+CLASS|JAVA\s+RESOURCE|MLE\s+MODULE)\s+` +
+	`(?:NAMED\s+)?(?:IF\s+NOT\s+EXISTS\s+)?` +
+	ident_pattern + `(?:\s*\.\s*` + ident_pattern + `)?`)
+
+var leading_comment_re = regexp.MustCompile(`^(?s)(\s+|--[^\n]*\n?|/\*.*?\*/)`)
+
+// strip_leading_comments removes whitespace and comments before the first
+// keyword of a statement.
+func strip_leading_comments(s string) string {
+	for {
+		loc := leading_comment_re.FindStringIndex(s)
+		if loc == nil || loc[1] == 0 {
+			return s
+		}
+		s = s[loc[1]:]
+	}
+}
+
+// normalize_identifier returns the dictionary form of a name: quoted names
+// keep their case, unquoted ones are upper-cased.
+func normalize_identifier(s string) string {
+	if strings.HasPrefix(s, `"`) && strings.HasSuffix(s, `"`) && len(s) >= 2 {
+		return s[1 : len(s)-1]
+	}
+	return strings.ToUpper(s)
+}
+
+// parse_object_ddl detects the object type, optional owner and name from a
+// CREATE statement. The owner is "" unless the DDL names one (or PUBLIC for
+// public synonyms).
+func parse_object_ddl(ddl string) (db_object, error) {
+	m := ddl_head_re.FindStringSubmatch(strip_leading_comments(ddl))
+	if m == nil {
+		return db_object{}, fmt.Errorf("cannot detect object type and name from DDL starting %q", ddl_preview(ddl))
+	}
+	obj := db_object{obj_type: strings.Join(strings.Fields(strings.ToUpper(m[2])), " ")}
+	if m[4] != "" {
+		obj.owner = normalize_identifier(m[3])
+		obj.name = normalize_identifier(m[4])
+	} else {
+		obj.name = normalize_identifier(m[3])
+	}
+	if m[1] != "" {
+		obj.owner = "PUBLIC"
+	}
+	return obj, nil
+}
+
+func ddl_preview(ddl string) string {
+	s := strings.Join(strings.Fields(strip_leading_comments(ddl)), " ")
+	if len(s) > 60 {
+		s = s[:60] + "..."
+	}
+	return s
+}
+
+// is_compiled_with_errors reports the OCI "success with compilation error"
+// result: the object was created but is INVALID.
+func is_compiled_with_errors(err error) bool {
+	return strings.Contains(err.Error(), "ORA-24344")
+}
+
+// object_status returns ALL_OBJECTS.STATUS, or "" when the object is absent.
+func object_status(ctx context.Context, db *sql.DB, obj db_object) (string, error) {
+	var status string
+	verify_q := `
+	  SELECT status
+	  FROM   all_objects
+	  WHERE  owner = :1
+	    AND  object_type = :2
+	    AND  object_name = :3`
+	err := db.QueryRowContext(ctx, verify_q, obj.owner, obj.obj_type, obj.name).Scan(&status)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("verify %s failed: %w", strings.ToLower(obj.obj_type), err)
+	}
+	return status, nil
+}
+
+// deploy_ddl compiles one CREATE statement in `owner`, verifies the status of
+// the object it creates and prints compiler errors if it is INVALID.
+// - ddl: complete CREATE [OR REPLACE] statement of any supported type, as
+// split by parse_script (PL/SQL keeps its final ";", SQL has none)
+// Package and type specs and bodies are separate objects and are verified
+// separately; deploying a spec also reports the status of an existing body,
+// which the new spec may have invalidated.
+// - loc: optional local file the statement came from, used to point compile
+// errors at file lines; nil shows ALL_SOURCE instead
+// An INVALID object is returned as *invalid_object_error.
+func deploy_ddl(ctx context.Context, db *sql.DB, owner, ddl string, loc *source_location) (db_object, error) {
+	obj, err := parse_object_ddl(ddl)
+	if err != nil {
+		return obj, err
+	}
+
+	// compile into target schema
+	if _, err := db.ExecContext(ctx, "ALTER SESSION SET CURRENT_SCHEMA = "+owner); err != nil {
+		return obj, fmt.Errorf("set current_schema failed: %w", err)
+	}
+	if obj.owner == "" {
+		obj.owner = strings.ToUpper(owner)
+	}
+
+	// compile
+	if _, err := db.ExecContext(ctx, ddl); err != nil && !is_compiled_with_errors(err) {
+		return obj, fmt.Errorf("create %s failed: %w", strings.ToLower(obj.obj_type), err)
+	}
+
+	// verify
+	status, err := object_status(ctx, db, obj)
+	if err != nil {
+		return obj, err
+	}
+	if status == "" {
+		return obj, fmt.Errorf("%s %s not found in ALL_OBJECTS after create", strings.ToLower(obj.obj_type), obj.name)
+	}
+	fmt.Printf("🧩 %s %s status: %s\n", obj.label(), obj.name, status)
+
+	// dump errors if invalid
+	if status == "INVALID" {
+		errs, err := dump_compile_errors(ctx, db, obj.owner, obj.obj_type, obj.name, loc)
+		if err != nil {
+			return obj, err
+		}
+		return obj, &invalid_object_error{obj, errs}
+	}
+
+	if obj.obj_type == "PACKAGE" || obj.obj_type == "TYPE" {
+		body := db_object{obj.owner, obj.obj_type + " BODY", obj.name}
+		body_status, err := object_status(ctx, db, body)
+		if err != nil {
+			return obj, err
+		}
+		if body_status != "" {
+			fmt.Printf("🧩 %s %s status: %s\n", body.label(), body.name, body_status)
+			if body_status == "INVALID" {
+				fmt.Printf("⚠️ %s %s needs to be recompiled or redeployed\n", strings.ToLower(body.obj_type), body.name)
+			}
+		}
+	}
+
+	return obj, nil
+}
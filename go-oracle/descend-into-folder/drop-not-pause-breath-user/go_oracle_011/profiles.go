@@ -0,0 +1,374 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+var resource_limit_names = map[string]bool{
+	"COMPOSITE_LIMIT":           true,
+	"SESSIONS_PER_USER":         true,
+	"CPU_PER_SESSION":           true,
+	"CPU_PER_CALL":              true,
+	"LOGICAL_READS_PER_SESSION": true,
+	"LOGICAL_READS_PER_CALL":    true,
+	"IDLE_TIME":                 true,
+	"CONNECT_TIME":              true,
+	"PRIVATE_SGA":               true,
+}
+
+var password_limit_names = map[string]bool{
+	"FAILED_LOGIN_ATTEMPTS":  true,
+	"PASSWORD_LIFE_TIME":     true,
+	"PASSWORD_REUSE_TIME":    true,
+	"PASSWORD_REUSE_MAX":     true,
+	"PASSWORD_LOCK_TIME":     true,
+	"PASSWORD_GRACE_TIME":    true,
+	"INACTIVE_ACCOUNT_TIME":  true,
+	"PASSWORD_ROLLOVER_TIME": true,
+}
+
+type profiles_yaml struct {
+	Profiles []profile_spec `yaml:"profiles"`
+}
+
+// profile_spec is one database profile. Limit values are written as in
+// CREATE PROFILE (numbers, UNLIMITED, DEFAULT; password times may also be
+// fractions of a day such as 1/24, PRIVATE_SGA a size such as 512K). users lists account names or LIKE
+// patterns that should be assigned the profile.
+type profile_spec struct {
+	Name                     string         `yaml:"name"`
+	Resource_limits          map[string]any `yaml:"resource_limits"`
+	Password_limits          map[string]any `yaml:"password_limits"`
+	Password_verify_function string         `yaml:"password_verify_function"`
+	Users                    []string       `yaml:"users"`
+}
+
+var limit_value_re = regexp.MustCompile(`^(UNLIMITED|DEFAULT|\d+(\.\d+)?(/\d+)?)$`)
+
+// private_sga_re is the PRIVATE_SGA grammar: a size in bytes with an
+// optional K, M, G, T, P or E suffix.
+var private_sga_re = regexp.MustCompile(`^(UNLIMITED|DEFAULT|\d+[KMGTPE]?)$`)
+
+// limit_number returns the numeric value of a limit: fractions are divided
+// out and size suffixes multiplied in, so that 1/24 matches the .0416 and
+// 10K the 10240 that DBA_PROFILES shows. ok is false for UNLIMITED,
+// DEFAULT and anything else that is not a number.
+func limit_number(s string) (float64, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if i := strings.IndexAny(s, "KMGTPE"); i > 0 && i == len(s)-1 {
+		n, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, false
+		}
+		return n * math.Pow(1024, float64(strings.IndexByte("KMGTPE", s[i])+1)), true
+	}
+	num, den, is_fraction := strings.Cut(s, "/")
+	n, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, false
+	}
+	if is_fraction {
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d == 0 {
+			return 0, false
+		}
+		n /= d
+	}
+	return n, true
+}
+
+// same_limit reports whether a desired and a stored limit are equal. Numbers
+// are compared by value with the four decimals DBA_PROFILES keeps.
+func same_limit(want, have string) bool {
+	w, w_ok := limit_number(want)
+	h, h_ok := limit_number(have)
+	if w_ok && h_ok {
+		return math.Abs(w-h) < 0.0001
+	}
+	return strings.EqualFold(strings.TrimSpace(want), strings.TrimSpace(have))
+}
+
+// limits returns the desired DBA_PROFILES rows keyed by RESOURCE_NAME. The
+// values are checked against the limit grammar, as they go into DDL as is.
+func (ps profile_spec) limits() (map[string]string, error) {
+	out := map[string]string{}
+	add := func(m map[string]any, valid map[string]bool, kind string) error {
+		for k, v := range m {
+			key := strings.ToUpper(strings.TrimSpace(k))
+			if !valid[key] {
+				return fmt.Errorf("profile %s: %q is not a %s limit", ps.Name, k, kind)
+			}
+			value := strings.ToUpper(strings.TrimSpace(fmt.Sprint(v)))
+			value_re := limit_value_re
+			if key == "PRIVATE_SGA" {
+				value_re = private_sga_re
+			}
+			if !value_re.MatchString(value) {
+				return fmt.Errorf("profile %s: %s %q is not a number, UNLIMITED or DEFAULT", ps.Name, key, fmt.Sprint(v))
+			}
+			out[key] = value
+		}
+		return nil
+	}
+	if err := add(ps.Resource_limits, resource_limit_names, "resource"); err != nil {
+		return nil, err
+	}
+	if err := add(ps.Password_limits, password_limit_names, "password"); err != nil {
+		return nil, err
+	}
+	if ps.Password_verify_function != "" {
+		fn, err := check_identifier("password verify function", ps.Password_verify_function)
+		if err != nil {
+			return nil, fmt.Errorf("profile %s: %w", ps.Name, err)
+		}
+		out["PASSWORD_VERIFY_FUNCTION"] = fn // NULL and DEFAULT pass as names
+	}
+	return out, nil
+}
+
+func load_profiles(path string) ([]profile_spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p profiles_yaml
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return p.Profiles, nil
+}
+
+func run_profiles(args []string) error {
+	return run_group("profiles", []command{
+		{"apply", "diff profiles.yaml against DBA_PROFILES, create/alter profiles, assign users", run_profiles_apply},
+		{"report", "list users with their profile, status and password expiry", run_profiles_report},
+	}, args)
+}
+
+func run_profiles_apply(args []string) error {
+	fs := flag.NewFlagSet("profiles apply", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	spec_path := fs.String("spec", "profiles.yaml", "YAML file with profiles")
+	dry_run := fs.Bool("dry-run", false, "print the statements without executing them")
+	fs.Parse(args)
+
+	specs, err := load_profiles(*spec_path)
+	if err != nil {
+		return fmt.Errorf("could not load profiles YAML: %w", err)
+	}
+	// names and limits go into DDL: check them all before connecting
+	wants := make([]map[string]string, len(specs))
+	for i := range specs {
+		if strings.TrimSpace(specs[i].Name) == "" {
+			return fmt.Errorf("profile without a name in %s", *spec_path)
+		}
+		if specs[i].Name, err = check_identifier("profile", specs[i].Name); err != nil {
+			return err
+		}
+		if wants[i], err = specs[i].limits(); err != nil {
+			return err
+		}
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	var stmts []string
+	for i, ps := range specs {
+		want := wants[i]
+		have, err := current_profile_limits(ctx, db, ps.Name)
+		if err != nil {
+			return err
+		}
+		stmt := profile_statement(ps.Name, want, have)
+		if stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+
+		assign, err := profile_assignments(ctx, db, ps)
+		if err != nil {
+			return err
+		}
+		stmts = append(stmts, assign...)
+	}
+
+	if len(stmts) == 0 {
+		fmt.Println("✅ profiles are up to date")
+		return nil
+	}
+
+	fail_count := 0
+	for _, stmt := range stmts {
+		if *dry_run {
+			fmt.Printf("🔎 [dry-run] %s\n", stmt)
+			continue
+		}
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			fmt.Printf("❌ %s (error: %v)\n", stmt, err)
+			fail_count++
+			continue
+		}
+		fmt.Printf("✅ %s\n", stmt)
+	}
+	if fail_count > 0 {
+		return fmt.Errorf("%d profile statement(s) failed", fail_count)
+	}
+	return nil
+}
+
+// current_profile_limits returns the DBA_PROFILES rows of a profile, or nil
+// when the profile does not exist yet.
+func current_profile_limits(ctx context.Context, db *sql.DB, name string) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT resource_name, limit
+		FROM   dba_profiles
+		WHERE  profile = :1`, name)
+	if err != nil {
+		return nil, fmt.Errorf("query dba_profiles failed: %w", err)
+	}
+	defer rows.Close()
+
+	var have map[string]string
+	for rows.Next() {
+		var resource, limit string
+		if err := rows.Scan(&resource, &limit); err != nil {
+			return nil, err
+		}
+		if have == nil {
+			have = map[string]string{}
+		}
+		have[resource] = limit
+	}
+	return have, rows.Err()
+}
+
+// profile_statement prints the difference between want and have and returns
+// the CREATE or ALTER PROFILE statement that closes it ("" when equal).
+func profile_statement(name string, want, have map[string]string) string {
+	keys := make([]string, 0, len(want))
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var clauses []string
+	for _, k := range keys {
+		old, ok := have[k]
+		if have != nil && ok && same_limit(want[k], old) {
+			continue
+		}
+		if have != nil {
+			fmt.Printf("🔧 %s %-25s %s -> %s\n", name, k, old, want[k])
+		}
+		clauses = append(clauses, k+" "+want[k])
+	}
+
+	if have == nil {
+		fmt.Printf("🆕 profile %s does not exist\n", name)
+		if len(clauses) == 0 {
+			clauses = []string{"SESSIONS_PER_USER DEFAULT"}
+		}
+		return fmt.Sprintf("CREATE PROFILE %s LIMIT %s", name, strings.Join(clauses, " "))
+	}
+	if len(clauses) == 0 {
+		fmt.Printf("✅ profile %s matches\n", name)
+		return ""
+	}
+	return fmt.Sprintf("ALTER PROFILE %s LIMIT %s", name, strings.Join(clauses, " "))
+}
+
+// profile_assignments returns ALTER USER ... PROFILE statements for the
+// spec's users that are not on the profile yet.
+func profile_assignments(ctx context.Context, db *sql.DB, ps profile_spec) ([]string, error) {
+	var stmts []string
+	for _, u := range ps.Users {
+		u = strings.ToUpper(strings.TrimSpace(u))
+		if u == "" {
+			continue
+		}
+		rows, err := db.QueryContext(ctx, `
+			SELECT username
+			FROM   dba_users
+			WHERE  username LIKE :1 ESCAPE '\'
+			  AND  profile <> :2
+			  AND  oracle_maintained = 'N'
+			ORDER BY username`, u, ps.Name)
+		if err != nil {
+			return nil, fmt.Errorf("resolve users for profile %s failed: %w", ps.Name, err)
+		}
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER USER %s PROFILE %s", quote_identifier(name), ps.Name))
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return stmts, nil
+}
+
+func run_profiles_report(args []string) error {
+	fs := flag.NewFlagSet("profiles report", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	pattern := fs.String("pattern", default_account_pattern, `LIKE pattern on USERNAME ("\_" is a literal "_")`)
+	fs.Parse(args)
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// PASSWORD_LIFE_TIME DEFAULT means "whatever the DEFAULT profile says"
+	rows, err := db.QueryContext(ctx, `
+		SELECT u.username, u.profile, u.account_status, u.expiry_date,
+		       CASE WHEN p.limit = 'DEFAULT' THEN d.limit ELSE p.limit END
+		FROM   dba_users u
+		JOIN   dba_profiles p ON p.profile = u.profile AND p.resource_name = 'PASSWORD_LIFE_TIME'
+		JOIN   dba_profiles d ON d.profile = 'DEFAULT' AND d.resource_name = 'PASSWORD_LIFE_TIME'
+		WHERE  u.username LIKE :1 ESCAPE '\'
+		ORDER BY u.profile, u.username`, strings.ToUpper(*pattern))
+	if err != nil {
+		return fmt.Errorf("profile report query failed: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Printf("%-30s %-40s %-20s %-19s %s\n", "PROFILE", "USERNAME", "ACCOUNT_STATUS", "EXPIRY_DATE", "PASSWORD_LIFE_TIME")
+	count := 0
+	for rows.Next() {
+		var user, profile, status, life_time string
+		var expiry sql.NullTime
+		if err := rows.Scan(&user, &profile, &status, &expiry, &life_time); err != nil {
+			return err
+		}
+		fmt.Printf("%-30s %-40s %-20s %-19s %s\n", profile, user, status, format_null_time(expiry), life_time)
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	fmt.Printf("📊 %d account(s)\n", count)
+	return nil
+}
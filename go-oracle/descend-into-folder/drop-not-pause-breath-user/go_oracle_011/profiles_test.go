@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestSameLimit(t *testing.T) {
+	for _, c := range []struct {
+		want, have string
+		same       bool
+	}{
+		{"1/24", ".0416", true},
+		{"1/24", ".0417", true},
+		{"1/24", "1", false},
+		{"0.5", ".5", true},
+		{"10", "10", true},
+		{"10K", "10240", true},
+		{"1M", "1048576", true},
+		{"1M", "1024", false},
+		{"UNLIMITED", "UNLIMITED", true},
+		{"UNLIMITED", "DEFAULT", false},
+		{"10", "DEFAULT", false},
+	} {
+		if got := same_limit(c.want, c.have); got != c.same {
+			t.Errorf("same_limit(%q, %q) = %v, want %v", c.want, c.have, got, c.same)
+		}
+	}
+}
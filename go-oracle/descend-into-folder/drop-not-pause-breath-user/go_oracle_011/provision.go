@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/PeterCullenBurbery/go_functions_002/v5/date_time_functions"
+	"github.com/goccy/go-yaml"
+)
+
+type roles_yaml struct {
+	Granted_roles []string `yaml:"granted_roles"`
+}
+
+type sys_privs_yaml struct {
+	System_privileges []string `yaml:"system_privileges"`
+}
+
+// provision_spec holds the optional per-user settings applied after the
+// role and privilege grants. Every section may be omitted.
+type provision_spec struct {
+	Profile              string        `yaml:"profile"` // database profile for the new user (see profiles.yaml)
+	Default_tablespace   string        `yaml:"default_tablespace"`
+	Temporary_tablespace string        `yaml:"temporary_tablespace"`
+	Quotas               []quota_spec  `yaml:"quotas"`
+	Proxy_grants         []proxy_grant `yaml:"proxy_grants"`
+}
+
+// quota_spec is one "QUOTA <quota> ON <tablespace>" clause; quota is a size
+// such as 500M or UNLIMITED.
+type quota_spec struct {
+	Tablespace string `yaml:"tablespace"`
+	Quota      string `yaml:"quota"`
+}
+
+// storage_statements returns the ALTER USER statements for the spec's
+// tablespace settings, validating names and sizes first.
+func (spec *provision_spec) storage_statements(username string) ([]string, error) {
+	var stmts []string
+	if spec.Default_tablespace != "" {
+		ts, err := check_identifier("default tablespace", spec.Default_tablespace)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER USER %s DEFAULT TABLESPACE %s", username, ts))
+	}
+	if spec.Temporary_tablespace != "" {
+		ts, err := check_identifier("temporary tablespace", spec.Temporary_tablespace)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER USER %s TEMPORARY TABLESPACE %s", username, ts))
+	}
+	for _, q := range spec.Quotas {
+		ts, err := check_identifier("quota tablespace", q.Tablespace)
+		if err != nil {
+			return nil, err
+		}
+		size, err := check_size("quota", q.Quota, true)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER USER %s QUOTA %s ON %s", username, size, ts))
+	}
+	return stmts, nil
+}
+
+// without_unlimited_tablespace drops UNLIMITED TABLESPACE from the system
+// privileges: with quotas in the spec it would make them meaningless.
+func without_unlimited_tablespace(privs []string) []string {
+	var out []string
+	for _, p := range privs {
+		if strings.EqualFold(strings.Join(strings.Fields(p), " "), "UNLIMITED TABLESPACE") {
+			fmt.Println("ℹ️ UNLIMITED TABLESPACE not granted: the spec sets quotas")
+			continue
+		}
+		out = append(out, p)
+	}
+	return out
+}
+
+// load_provision_spec reads the spec file; a missing file is an empty spec.
+func load_provision_spec(path string) (*provision_spec, error) {
+	var spec provision_spec
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &spec, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, err
+	}
+	return &spec, nil
+}
+
+func load_roles(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r roles_yaml
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return r.Granted_roles, nil
+}
+
+func load_sys_privs(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p sys_privs_yaml
+	if err := yaml.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return p.System_privileges, nil
+}
+
+// run_provision is the original go_oracle_010 flow: create a timestamped
+// user, grant the YAML role and privilege lists, and deploy the code in
+// -code-dir.
+func run_provision(args []string) error {
+	fs := flag.NewFlagSet("provision", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	password := fs.String("password", "f", "password for the new user")
+	roles_yaml_path := fs.String("roles", "granted-roles.yaml", "YAML file with granted_roles")
+	sys_privs_yaml_path := fs.String("sys-privs", "system-privileges-without-sysdba-et-al.yaml", "YAML file with system_privileges")
+	grant_batch_size := fs.Int("grant-batch-size", default_grant_batch_size, "grants sent per PL/SQL block")
+	code_dir := fs.String("code-dir", "db", "directory with the database code to deploy (see deploy)")
+	spec_path := fs.String("spec", "provision-spec.yaml", "optional provisioning spec (proxy grants, ...)")
+	tf := add_template_flags(fs)
+	fs.Parse(args)
+
+	spec, err := load_provision_spec(*spec_path)
+	if err != nil {
+		return fmt.Errorf("could not load provisioning spec: %w", err)
+	}
+
+	// 1) generate username
+	gen, err := date_time_functions.Generate_prefixed_timestamp("user_slash_schema")
+	if err != nil {
+		return fmt.Errorf("failed to generate timestamped username: %w", err)
+	}
+	username := sanitize_oracle_identifier(gen)
+
+	// load the code now so that broken files and templates fail before the
+	// user is created; it is loaded again once the session is known
+	tc, err := tf.context(cf, username)
+	if err != nil {
+		return err
+	}
+	code_manifest := filepath.Join(*code_dir, default_manifest_name)
+	if _, _, err := load_deploy_units(*code_dir, code_manifest, nil, tc); err != nil {
+		return fmt.Errorf("could not load database code: %w", err)
+	}
+
+	// validate the storage settings before anything is created
+	if _, err := spec.storage_statements(username); err != nil {
+		return fmt.Errorf("invalid provisioning spec: %w", err)
+	}
+	if err := check_proxy_grants(spec.Proxy_grants); err != nil {
+		return fmt.Errorf("invalid provisioning spec: %w", err)
+	}
+	if spec.Profile != "" {
+		if spec.Profile, err = check_identifier("profile", spec.Profile); err != nil {
+			return fmt.Errorf("invalid provisioning spec: %w", err)
+		}
+	}
+
+	// 2) connect as sysdba, 3) switch to pdb
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// 4) create user (with fallback on long identifier)
+	createStmt := fmt.Sprintf("CREATE USER %s IDENTIFIED BY %s", username, *password)
+	if _, err := db.ExecContext(ctx, createStmt); err != nil {
+		if is_identifier_too_long(err) {
+			// First retry at 128
+			short := truncate_identifier(username, MAX_IDENTIFIER_LEN)
+			if short != username {
+				fmt.Printf("⚠️ identifier too long; retrying with: %s\n", short)
+				username = short
+				if _, err2 := db.ExecContext(ctx, fmt.Sprintf("CREATE USER %s IDENTIFIED BY %s", username, *password)); err2 != nil {
+					// Optional legacy fallback (30) for pre-12.2 databases
+					if is_identifier_too_long(err2) {
+						legacy := truncate_identifier(username, LEGACY_IDENTIFIER_LEN)
+						if legacy != username {
+							fmt.Printf("⚠️ still too long; legacy retry with: %s\n", legacy)
+							username = legacy
+							if _, err3 := db.ExecContext(ctx, fmt.Sprintf("CREATE USER %s IDENTIFIED BY %s", username, *password)); err3 != nil {
+								return fmt.Errorf("CREATE USER failed after legacy fallback: %w", err3)
+							}
+						} else {
+							return fmt.Errorf("CREATE USER failed after 128 and legacy attempts: %w", err2)
+						}
+					} else {
+						return fmt.Errorf("CREATE USER failed after 128 attempt: %w", err2)
+					}
+				}
+			} else {
+				return fmt.Errorf("CREATE USER failed (length unchanged): %w", err)
+			}
+		} else {
+			return fmt.Errorf("CREATE USER failed: %w", err)
+		}
+	}
+	// minimal logon privilege (can be redundant if CONNECT role is granted later)
+	if _, err := db.ExecContext(ctx, "GRANT CREATE SESSION TO "+username+" CONTAINER=CURRENT"); err != nil {
+		fmt.Printf("⚠️ grant CREATE SESSION failed (continuing): %v\n", err)
+	}
+	fmt.Printf("🎉 Created user: %s (password: %s)\n", username, *password)
+
+	if spec.Profile != "" {
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER USER %s PROFILE %s", username, spec.Profile)); err != nil {
+			return fmt.Errorf("assign profile %s failed: %w", spec.Profile, err)
+		}
+		fmt.Printf("🪪 profile %s -> %s\n", spec.Profile, username)
+	}
+
+	// tablespaces and quotas (rebuilt: username may have been truncated)
+	storage, _ := spec.storage_statements(username)
+	for _, stmt := range storage {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("%s failed: %w", stmt, err)
+		}
+		fmt.Printf("🗄️ %s\n", stmt)
+	}
+
+	// 5) load YAML lists
+	roles, err := load_roles(*roles_yaml_path)
+	if err != nil {
+		return fmt.Errorf("could not load roles YAML: %w", err)
+	}
+	sys_privs, err := load_sys_privs(*sys_privs_yaml_path)
+	if err != nil {
+		return fmt.Errorf("could not load system privileges YAML: %w", err)
+	}
+	if len(spec.Quotas) > 0 {
+		sys_privs = without_unlimited_tablespace(sys_privs)
+	}
+
+	// 6) + 7) grant roles and system privileges in one batched call
+	items := append(grant_list("role", roles), grant_list("sys priv", sys_privs)...)
+	results, err := grant_items(ctx, db, username, items, *grant_batch_size)
+	if err != nil {
+		return err
+	}
+	ok_count, fail_count := report_grants(username, "role", items, results)
+	fmt.Printf("📊 roles granted OK=%d, failed=%d\n", ok_count, fail_count)
+	ok_count, fail_count = report_grants(username, "sys priv", items, results)
+	fmt.Printf("📊 system privileges granted OK=%d, failed=%d\n", ok_count, fail_count)
+
+	// 8) proxy users allowed to connect through the new user
+	if err := apply_proxy_grants(ctx, db, username, spec.Proxy_grants); err != nil {
+		return err
+	}
+
+	// 9) deploy the database code (Java source, functions, smoke tests),
+	// rendering templates for the final username and container
+	tc.Username = username
+	if err := tc.connect(ctx, db); err != nil {
+		return err
+	}
+	units, manifest, err := load_deploy_units(*code_dir, code_manifest, nil, tc)
+	if err != nil {
+		return fmt.Errorf("could not load database code: %w", err)
+	}
+	_, err = deploy_units(ctx, db, username, *code_dir, units, manifest, false, nil)
+	return err
+}
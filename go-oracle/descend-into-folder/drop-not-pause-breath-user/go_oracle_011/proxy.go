@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"strings"
+)
+
+// proxy_grant lets `proxy` open sessions as the provisioned user:
+//
+//	ALTER USER <user> GRANT CONNECT THROUGH <proxy> [WITH ROLE r1, r2]
+//
+// With create_if_missing the proxy account is created first using password.
+type proxy_grant struct {
+	Proxy             string   `yaml:"proxy"`
+	Roles             []string `yaml:"roles"`
+	Create_if_missing bool     `yaml:"create_if_missing"`
+	Password          string   `yaml:"password"`
+}
+
+// checked validates the proxy and role names, which are spliced into DDL,
+// and the password, and returns the grant with upper-cased names.
+func (pg proxy_grant) checked() (proxy_grant, error) {
+	proxy, err := check_identifier("proxy user", pg.Proxy)
+	if err != nil {
+		return pg, err
+	}
+	out := pg
+	out.Proxy = proxy
+	out.Roles = nil
+	for _, r := range pg.Roles {
+		if strings.TrimSpace(r) == "" {
+			continue
+		}
+		role, err := check_identifier("proxy role", r)
+		if err != nil {
+			return pg, fmt.Errorf("proxy %s: %w", proxy, err)
+		}
+		out.Roles = append(out.Roles, role)
+	}
+	if strings.Contains(pg.Password, `"`) {
+		return pg, fmt.Errorf("proxy %s: password contains a double quote, which Oracle passwords cannot", proxy)
+	}
+	return out, nil
+}
+
+// check_proxy_grants validates every grant of a spec up front.
+func check_proxy_grants(grants []proxy_grant) error {
+	for _, pg := range grants {
+		if strings.TrimSpace(pg.Proxy) == "" {
+			continue
+		}
+		if _, err := pg.checked(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statement renders the grant; pg must have passed checked.
+func (pg proxy_grant) statement(username string) string {
+	stmt := fmt.Sprintf("ALTER USER %s GRANT CONNECT THROUGH %s", username, pg.Proxy)
+	if len(pg.Roles) > 0 {
+		stmt += " WITH ROLE " + strings.Join(pg.Roles, ", ")
+	}
+	return stmt
+}
+
+// apply_proxy_grants runs every proxy grant for username and reports each
+// one; the remaining grants are still tried after a failure, which is
+// returned as an error at the end.
+func apply_proxy_grants(ctx context.Context, db *sql.DB, username string, grants []proxy_grant) error {
+	if len(grants) == 0 {
+		return nil
+	}
+
+	ok_count := 0
+	fail_count := 0
+	for _, g := range grants {
+		if strings.TrimSpace(g.Proxy) == "" {
+			continue
+		}
+		pg, err := g.checked()
+		if err != nil {
+			fmt.Printf("❌ proxy grant: %v\n", err)
+			fail_count++
+			continue
+		}
+		if pg.Create_if_missing {
+			if err := ensure_proxy_user(ctx, db, pg); err != nil {
+				fmt.Printf("❌ proxy user %-35s (error: %v)\n", pg.Proxy, err)
+				fail_count++
+				continue
+			}
+		}
+		if _, err := db.ExecContext(ctx, pg.statement(username)); err != nil {
+			fmt.Printf("❌ connect through %-35s -> %s (error: %v)\n", pg.Proxy, username, err)
+			fail_count++
+			continue
+		}
+		fmt.Printf("✅ connect through %-35s -> %s\n", pg.Proxy, username)
+		ok_count++
+	}
+	fmt.Printf("📊 proxy grants OK=%d, failed=%d\n", ok_count, fail_count)
+	if fail_count > 0 {
+		return fmt.Errorf("%d proxy grant(s) failed", fail_count)
+	}
+	return nil
+}
+
+// ensure_proxy_user creates the proxy account; pg must have passed checked.
+func ensure_proxy_user(ctx context.Context, db *sql.DB, pg proxy_grant) error {
+	var n int
+	if err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM dba_users WHERE username = :1", pg.Proxy).Scan(&n); err != nil {
+		return fmt.Errorf("check proxy user failed: %w", err)
+	}
+	if n > 0 {
+		return nil
+	}
+	if pg.Password == "" {
+		return fmt.Errorf("proxy user %s does not exist and no password is given", pg.Proxy)
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE USER %s IDENTIFIED BY "%s"`, pg.Proxy, pg.Password)); err != nil {
+		return fmt.Errorf("CREATE USER failed: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, "GRANT CREATE SESSION TO "+pg.Proxy+" CONTAINER=CURRENT"); err != nil {
+		return fmt.Errorf("grant CREATE SESSION failed: %w", err)
+	}
+	fmt.Printf("🎉 Created proxy user: %s\n", pg.Proxy)
+	return nil
+}
+
+// run_whoami prints the USERENV identity of a session opened either as
+// SYSDBA or through a named connection profile, and optionally checks it.
+func run_whoami(args []string) error {
+	fs := flag.NewFlagSet("whoami", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	profile := fs.String("profile", "", "connection profile from the config file (default: SYSDBA connection)")
+	expect_user := fs.String("expect-session-user", "", "fail unless SESSION_USER equals this")
+	expect_proxy := fs.String("expect-proxy-user", "", "fail unless PROXY_USER equals this")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	var db *sql.DB
+	if *profile == "" {
+		var err error
+		if db, err = cf.open(ctx); err != nil {
+			return err
+		}
+	} else {
+		cfg, err := load_config(cf.config_path)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		oc, err := cfg.profile(*profile)
+		if err != nil {
+			return err
+		}
+		if db, err = open_connection(oc); err != nil {
+			return err
+		}
+	}
+	defer db.Close()
+
+	var session_user, current_schema, con_name, auth_method string
+	var proxy_user sql.NullString
+	q := `
+		SELECT SYS_CONTEXT('USERENV','SESSION_USER'),
+		       SYS_CONTEXT('USERENV','PROXY_USER'),
+		       SYS_CONTEXT('USERENV','CURRENT_SCHEMA'),
+		       SYS_CONTEXT('USERENV','CON_NAME'),
+		       SYS_CONTEXT('USERENV','AUTHENTICATION_METHOD')
+		FROM   dual`
+	if err := db.QueryRowContext(ctx, q).Scan(&session_user, &proxy_user, &current_schema, &con_name, &auth_method); err != nil {
+		return fmt.Errorf("USERENV query failed: %w", err)
+	}
+
+	fmt.Printf("👤 SESSION_USER:          %s\n", session_user)
+	fmt.Printf("🔀 PROXY_USER:            %s\n", null_or_dash(proxy_user))
+	fmt.Printf("📂 CURRENT_SCHEMA:        %s\n", current_schema)
+	fmt.Printf("📦 CON_NAME:              %s\n", con_name)
+	fmt.Printf("🔐 AUTHENTICATION_METHOD: %s\n", auth_method)
+
+	if *expect_user != "" && !strings.EqualFold(*expect_user, session_user) {
+		return fmt.Errorf("SESSION_USER is %s, expected %s", session_user, strings.ToUpper(*expect_user))
+	}
+	if *expect_proxy != "" && !strings.EqualFold(*expect_proxy, proxy_user.String) {
+		return fmt.Errorf("PROXY_USER is %s, expected %s", null_or_dash(proxy_user), strings.ToUpper(*expect_proxy))
+	}
+	if *expect_user != "" || *expect_proxy != "" {
+		fmt.Println("✅ session identity matches")
+	}
+	return nil
+}
+
+func null_or_dash(s sql.NullString) string {
+	if !s.Valid || s.String == "" {
+		return "-"
+	}
+	return s.String
+}
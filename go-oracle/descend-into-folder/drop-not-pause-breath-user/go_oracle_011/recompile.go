@@ -0,0 +1,324 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// plsql_warning_types accept a PLSQL_WARNINGS compiler setting on
+// ALTER ... COMPILE.
+var plsql_warning_types = map[string]bool{
+	"FUNCTION": true, "PROCEDURE": true, "PACKAGE": true, "PACKAGE BODY": true,
+	"TYPE": true, "TYPE BODY": true, "TRIGGER": true,
+}
+
+// compile_rank orders objects that do not depend on each other: specs and
+// standalone units before bodies and views.
+var compile_rank = map[string]int{
+	"TYPE": 0, "PACKAGE": 1, "FUNCTION": 2, "PROCEDURE": 2, "LIBRARY": 2, "JAVA SOURCE": 3, "JAVA CLASS": 3,
+	"VIEW": 4, "MATERIALIZED VIEW": 5, "SYNONYM": 5, "TYPE BODY": 6, "PACKAGE BODY": 6, "TRIGGER": 7,
+}
+
+// invalid_objects lists the INVALID objects of owner, or of every
+// non-Oracle-maintained schema when owner is "".
+func invalid_objects(ctx context.Context, db *sql.DB, owner string) ([]db_object, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT o.owner, o.object_type, o.object_name
+		FROM   dba_objects o
+		LEFT JOIN dba_users u ON u.username = o.owner
+		WHERE  o.status = 'INVALID'
+		  AND  (:1 IS NULL OR o.owner = :2)
+		  AND  (:3 IS NOT NULL OR u.oracle_maintained = 'N')
+		ORDER BY o.owner, o.object_name, o.object_type`, owner, owner, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query invalid objects failed: %w", err)
+	}
+	defer rows.Close()
+
+	var objs []db_object
+	for rows.Next() {
+		var o db_object
+		if err := rows.Scan(&o.owner, &o.obj_type, &o.name); err != nil {
+			return nil, err
+		}
+		objs = append(objs, o)
+	}
+	return objs, rows.Err()
+}
+
+// order_by_dba_dependencies sorts objs so that every object is compiled
+// after the invalid objects it references in DBA_DEPENDENCIES. Independent
+// objects follow compile_rank; a cycle is broken in that order.
+func order_by_dba_dependencies(ctx context.Context, db *sql.DB, objs []db_object) ([]db_object, error) {
+	index := map[db_object]int{}
+	owners := map[string]bool{}
+	for i, o := range objs {
+		index[o] = i
+		owners[o.owner] = true
+	}
+	deps := make([]map[int]bool, len(objs))
+	for i := range deps {
+		deps[i] = map[int]bool{}
+	}
+
+	for owner := range owners {
+		rows, err := db.QueryContext(ctx, `
+			SELECT type, name, referenced_owner, referenced_type, referenced_name
+			FROM   dba_dependencies
+			WHERE  owner = :1`, owner)
+		if err != nil {
+			return nil, fmt.Errorf("query dependencies failed: %w", err)
+		}
+		for rows.Next() {
+			var from, to db_object
+			var ref_owner, ref_type sql.NullString
+			if err := rows.Scan(&from.obj_type, &from.name, &ref_owner, &ref_type, &to.name); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			from.owner = owner
+			to.owner, to.obj_type = ref_owner.String, ref_type.String
+			i, ok_from := index[from]
+			j, ok_to := index[to]
+			if ok_from && ok_to && i != j {
+				deps[i][j] = true
+			}
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	remaining := make([]int, len(objs))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	sort.SliceStable(remaining, func(a, b int) bool {
+		return compile_rank[objs[remaining[a]].obj_type] < compile_rank[objs[remaining[b]].obj_type]
+	})
+
+	done := make([]bool, len(objs))
+	var ordered []db_object
+	for len(ordered) < len(objs) {
+		next := -1
+		for _, i := range remaining {
+			if done[i] {
+				continue
+			}
+			ready := true
+			for j := range deps[i] {
+				if !done[j] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			for _, i := range remaining {
+				if !done[i] {
+					next = i
+					break
+				}
+			}
+			fmt.Printf("⚠️ dependency cycle involving %s; compiling in type order\n", objs[next])
+		}
+		done[next] = true
+		ordered = append(ordered, objs[next])
+	}
+	return ordered, nil
+}
+
+// compile_statement returns the ALTER ... COMPILE statement for an object,
+// with the PLSQL_WARNINGS setting for PL/SQL units when warnings is set.
+func compile_statement(o db_object, warnings string) string {
+	name := quote_identifier(o.owner) + "." + quote_identifier(o.name)
+	var stmt string
+	switch o.obj_type {
+	case "PACKAGE", "TYPE":
+		stmt = fmt.Sprintf("ALTER %s %s COMPILE SPECIFICATION", o.obj_type, name)
+	case "PACKAGE BODY", "TYPE BODY":
+		stmt = fmt.Sprintf("ALTER %s %s COMPILE BODY", strings.TrimSuffix(o.obj_type, " BODY"), name)
+	case "JAVA CLASS":
+		stmt = fmt.Sprintf("ALTER JAVA CLASS %s RESOLVE", name)
+	case "SYNONYM":
+		if o.owner == "PUBLIC" {
+			return fmt.Sprintf("ALTER PUBLIC SYNONYM %s COMPILE", quote_identifier(o.name))
+		}
+		stmt = fmt.Sprintf("ALTER SYNONYM %s COMPILE", name)
+	default:
+		stmt = fmt.Sprintf("ALTER %s %s COMPILE", o.obj_type, name)
+	}
+	if warnings != "" && plsql_warning_types[o.obj_type] {
+		stmt += fmt.Sprintf(" PLSQL_WARNINGS = %s REUSE SETTINGS", quote_literal(warnings))
+	}
+	return stmt
+}
+
+func run_recompile(args []string) error {
+	fs := flag.NewFlagSet("recompile", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	rf := add_report_flags(fs)
+	owner := fs.String("owner", "", "schema to recompile")
+	all := fs.Bool("all", false, "recompile every non-Oracle-maintained schema of the container")
+	method := fs.String("method", "deps", "deps (ALTER ... COMPILE in DBA_DEPENDENCIES order) or utl_recomp")
+	threads := fs.Int("threads", 0, "utl_recomp: RECOMP_PARALLEL threads (0 = RECOMP_SERIAL)")
+	warnings := fs.String("warnings", "", "PLSQL_WARNINGS setting, e.g. 'ENABLE:ALL' or 'ENABLE:PERFORMANCE,DISABLE:5018'")
+	dry_run := fs.Bool("dry-run", false, "only print the objects and the compile order")
+	fs.Parse(args)
+
+	target := ""
+	if !*all {
+		var err error
+		if target, err = check_identifier("owner", *owner); err != nil {
+			return fmt.Errorf("-owner or -all is required: %w", err)
+		}
+	}
+	if *method != "deps" && *method != "utl_recomp" {
+		return fmt.Errorf("unknown -method %q (deps or utl_recomp)", *method)
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	before, err := invalid_objects(ctx, db, target)
+	if err != nil {
+		return err
+	}
+	if len(before) == 0 {
+		fmt.Println("✅ no INVALID objects")
+		return nil
+	}
+	fmt.Printf("🔎 %d INVALID object(s)\n", len(before))
+
+	if *method == "utl_recomp" {
+		if err := utl_recomp(ctx, db, target, *threads, *warnings, *dry_run); err != nil {
+			return err
+		}
+	} else {
+		ordered, err := order_by_dba_dependencies(ctx, db, before)
+		if err != nil {
+			return err
+		}
+		for _, o := range ordered {
+			stmt := compile_statement(o, *warnings)
+			if *dry_run {
+				fmt.Printf("🔎 [dry-run] %s\n", stmt)
+				continue
+			}
+			// compiled with errors is reported in the table below
+			if _, err := db.ExecContext(ctx, stmt); err != nil && !is_compiled_with_errors(err) {
+				fmt.Printf("❌ %s: %v\n", stmt, err)
+				continue
+			}
+			fmt.Printf("🔧 %s\n", stmt)
+		}
+	}
+	if *dry_run {
+		return nil
+	}
+
+	return report_recompile(ctx, db, before, *warnings != "", rf)
+}
+
+// utl_recomp recompiles with UTL_RECOMP. The PLSQL_WARNINGS setting is
+// applied to the session first; a NULL schema means the whole container.
+func utl_recomp(ctx context.Context, db *sql.DB, schema string, threads int, warnings string, dry_run bool) error {
+	if warnings != "" {
+		stmt := "ALTER SESSION SET PLSQL_WARNINGS = " + quote_literal(warnings)
+		if err := exec_or_print(ctx, db, dry_run, stmt); err != nil {
+			return err
+		}
+	}
+	var arg any
+	if schema != "" {
+		arg = schema
+	}
+	block := "BEGIN UTL_RECOMP.RECOMP_SERIAL(:1); END;"
+	binds := []any{arg}
+	if threads > 0 {
+		block = "BEGIN UTL_RECOMP.RECOMP_PARALLEL(:1, :2); END;"
+		binds = []any{threads, arg}
+	}
+	if dry_run {
+		fmt.Printf("🔎 [dry-run] %s schema=%v\n", block, arg)
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, block, binds...); err != nil {
+		return fmt.Errorf("UTL_RECOMP failed: %w", err)
+	}
+	fmt.Println("🔧 UTL_RECOMP finished")
+	return nil
+}
+
+// report_recompile prints the before/after status of every object that was
+// INVALID and the compile errors of those that still are. With warnings the
+// compiler warnings of the objects that became valid are shown as well.
+func report_recompile(ctx context.Context, db *sql.DB, before []db_object, warnings bool, rf *report_flags) error {
+	fmt.Printf("\n%-60s %-8s %s\n", "OBJECT", "BEFORE", "AFTER")
+	var still []db_object
+	gone := 0
+	for _, o := range before {
+		after, err := object_status(ctx, db, o)
+		if err != nil {
+			return err
+		}
+		mark := "✅"
+		switch after {
+		case "INVALID":
+			mark = "❌"
+			still = append(still, o)
+		case "":
+			after = "(gone)"
+			mark = "ℹ️"
+			gone++
+		}
+		fmt.Printf("%-60s %-8s %-8s %s\n", o.String(), "INVALID", after, mark)
+	}
+	fmt.Printf("📊 now valid=%d, still invalid=%d, gone=%d\n", len(before)-len(still)-gone, len(still), gone)
+
+	var diagnostics []compile_error
+	for _, o := range before {
+		is_still := slices.Contains(still, o)
+		if !is_still && !warnings {
+			continue
+		}
+		if !is_still {
+			// only print valid objects that have warnings
+			errs, err := fetch_compile_errors(ctx, db, o.owner, o.obj_type, o.name)
+			if err != nil {
+				return err
+			}
+			if len(errs) == 0 {
+				continue
+			}
+		}
+		fmt.Printf("\n🧩 %s\n", o)
+		errs, err := dump_compile_errors(ctx, db, o.owner, o.obj_type, o.name, nil)
+		if err != nil {
+			return err
+		}
+		diagnostics = append(diagnostics, errs...)
+	}
+	if err := rf.write(diagnostics); err != nil {
+		return err
+	}
+	if len(still) > 0 {
+		return fmt.Errorf("%d object(s) still INVALID", len(still))
+	}
+	return nil
+}
@@ -0,0 +1,927 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// schema_target is one side of a comparison, written as
+// [profile:][container/]owner. Without a profile the SYSDBA connection of
+// the config file is used.
+type schema_target struct {
+	profile   string
+	container string
+	owner     string
+}
+
+func parse_schema_target(s string) (schema_target, error) {
+	var t schema_target
+	if p, rest, ok := strings.Cut(s, ":"); ok {
+		t.profile, s = p, rest
+	}
+	if c, rest, ok := strings.Cut(s, "/"); ok {
+		t.container, s = c, rest
+	}
+	owner, err := check_identifier("owner", s)
+	if err != nil {
+		return t, err
+	}
+	t.owner = owner
+	return t, nil
+}
+
+func (t schema_target) String() string {
+	s := t.owner
+	if t.container != "" {
+		s = t.container + "/" + s
+	}
+	if t.profile != "" {
+		s = t.profile + ":" + s
+	}
+	return s
+}
+
+// open connects for the target: SYSDBA (in the target's container or the
+// -container default) or the named connection profile.
+func (t schema_target) open(ctx context.Context, cf *conn_flags) (*sql.DB, error) {
+	if t.profile == "" {
+		c := *cf
+		if t.container != "" {
+			c.container = t.container
+		}
+		return c.open(ctx)
+	}
+	cfg, err := load_config(cf.config_path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	oc, err := cfg.profile(t.profile)
+	if err != nil {
+		return nil, err
+	}
+	db, err := open_connection(oc)
+	if err != nil {
+		return nil, err
+	}
+	if t.container != "" {
+		if err := switch_container(ctx, db, t.container); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+type column_def struct {
+	table, column string
+	data_type     string
+	default_expr  string // DEFAULT or GENERATED clause
+	not_null      bool
+	special       bool // identity or virtual: not changed by the sync script
+}
+
+func (c column_def) String() string {
+	s := c.data_type
+	if c.default_expr != "" {
+		s += " " + c.default_expr
+	}
+	if c.not_null {
+		s += " NOT NULL"
+	}
+	return s
+}
+
+type constraint_def struct {
+	table, name string
+	def         string // e.g. PRIMARY KEY ("ID")
+	referential bool
+}
+
+type index_def struct {
+	table, name string
+	def         string // e.g. UNIQUE NORMAL ("A", "B" DESC)
+	generated   bool   // system-named
+	constraint  bool   // backs a constraint; created with it
+}
+
+// schema_snapshot is what one side of a diff contains. Maps are keyed so
+// that equal things on both sides have the same key; ddl is nil with
+// -no-ddl, and a grant category the session cannot read is missing.
+type schema_snapshot struct {
+	target      schema_target
+	objects     map[string]db_object // "TYPE NAME"
+	status      map[string]string
+	ddl         map[string]string
+	columns     map[string]column_def        // "TABLE.COLUMN"
+	constraints map[string]constraint_def    // "TABLE name" or "TABLE definition" for system names
+	indexes     map[string]index_def         // name, or "TABLE (columns)" for system names
+	grants      map[string]map[string]string // category -> GRANT text with {owner} for the schema -> WITH ... OPTION
+}
+
+var (
+	sequence_start_re = regexp.MustCompile(`\s+START WITH \d+`)
+	not_null_check_re = regexp.MustCompile(`^"[^"]+" IS NOT NULL$`)
+)
+
+// strip_owner removes the qualification with the snapshot's own schema, so
+// that two schemas with different names compare equal and the sync script
+// runs under CURRENT_SCHEMA.
+func strip_owner(s, owner string) string {
+	return strings.ReplaceAll(s, quote_identifier(owner)+".", "")
+}
+
+func object_key(o db_object) string {
+	return o.obj_type + " " + o.name
+}
+
+// take_snapshot reads every category for the target's owner.
+func take_snapshot(ctx context.Context, db *sql.DB, t schema_target, with_ddl bool) (*schema_snapshot, error) {
+	s := &schema_snapshot{target: t}
+	owner := t.owner
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT object_type, object_name, status
+		FROM   all_objects
+		WHERE  owner = :1
+		  AND  generated = 'N'
+		  AND  secondary = 'N'
+		  AND  object_name NOT LIKE 'BIN$%'
+		  AND  object_type NOT IN ('LOB', 'LOB PARTITION', 'TABLE PARTITION', 'TABLE SUBPARTITION',
+		                           'INDEX PARTITION', 'INDEX SUBPARTITION', 'JAVA CLASS', 'JAVA RESOURCE')`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("%s: query all_objects failed: %w", t, err)
+	}
+	s.objects = map[string]db_object{}
+	s.status = map[string]string{}
+	for rows.Next() {
+		o := db_object{owner: owner}
+		var status string
+		if err := rows.Scan(&o.obj_type, &o.name, &status); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.objects[object_key(o)] = o
+		s.status[object_key(o)] = status
+	}
+	err = rows.Err()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if with_ddl {
+		// foreign keys are compared as constraints and added after all
+		// tables exist, so table DDL leaves them out
+		if err := set_metadata_transforms(ctx, db, export_options{no_ref_constraints: true}); err != nil {
+			return nil, err
+		}
+		s.ddl = map[string]string{}
+		for key, o := range s.objects {
+			if _, ok := ddl_layout[o.obj_type]; !ok {
+				continue
+			}
+			ddl, err := object_ddl(ctx, db, o, false)
+			if err != nil {
+				fmt.Printf("⚠️ %s: no DDL for %s: %v\n", t, o, err)
+				continue
+			}
+			ddl = strip_owner(ddl, owner)
+			if o.obj_type == "SEQUENCE" || o.obj_type == "TABLE" {
+				ddl = sequence_start_re.ReplaceAllString(ddl, "")
+			}
+			s.ddl[key] = ddl
+		}
+	}
+
+	if s.columns, err = snapshot_columns(ctx, db, owner); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+	if s.constraints, err = snapshot_constraints(ctx, db, owner); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+	if s.indexes, err = snapshot_indexes(ctx, db, owner); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+	if s.grants, err = snapshot_grants(ctx, db, owner); err != nil {
+		return nil, fmt.Errorf("%s: %w", t, err)
+	}
+	return s, nil
+}
+
+// sql_data_type renders a column type the way it is written in DDL.
+func sql_data_type(data_type string, length, char_length int64, char_used string, precision, scale sql.NullInt64) string {
+	switch data_type {
+	case "VARCHAR2", "CHAR":
+		unit := "BYTE"
+		if char_used == "C" {
+			unit = "CHAR"
+		}
+		return fmt.Sprintf("%s(%d %s)", data_type, char_length, unit)
+	case "NVARCHAR2", "NCHAR":
+		return fmt.Sprintf("%s(%d)", data_type, char_length)
+	case "RAW":
+		return fmt.Sprintf("RAW(%d)", length)
+	case "FLOAT":
+		if precision.Valid {
+			return fmt.Sprintf("FLOAT(%d)", precision.Int64)
+		}
+	case "NUMBER":
+		switch {
+		case !precision.Valid && scale.Valid && scale.Int64 == 0:
+			return "NUMBER(*,0)"
+		case !precision.Valid:
+			return "NUMBER"
+		case !scale.Valid || scale.Int64 == 0:
+			return fmt.Sprintf("NUMBER(%d)", precision.Int64)
+		}
+		return fmt.Sprintf("NUMBER(%d,%d)", precision.Int64, scale.Int64)
+	}
+	return data_type
+}
+
+func snapshot_columns(ctx context.Context, db *sql.DB, owner string) (map[string]column_def, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.table_name, c.column_name, c.data_type, c.data_length, NVL(c.char_length, 0),
+		       NVL(c.char_used, 'B'), c.data_precision, c.data_scale, c.nullable, c.data_default,
+		       c.identity_column, c.virtual_column
+		FROM   all_tab_cols c
+		JOIN   all_tables t ON t.owner = c.owner AND t.table_name = c.table_name
+		WHERE  c.owner = :1
+		  AND  c.hidden_column = 'NO'
+		  AND  c.table_name NOT LIKE 'BIN$%'
+		ORDER BY c.table_name, c.column_id`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query columns failed: %w", err)
+	}
+	defer rows.Close()
+
+	cols := map[string]column_def{}
+	for rows.Next() {
+		var c column_def
+		var data_type, char_used, nullable, identity, virtual string
+		var length, char_length int64
+		var precision, scale sql.NullInt64
+		var default_expr sql.NullString
+		if err := rows.Scan(&c.table, &c.column, &data_type, &length, &char_length, &char_used,
+			&precision, &scale, &nullable, &default_expr, &identity, &virtual); err != nil {
+			return nil, err
+		}
+		c.data_type = sql_data_type(data_type, length, char_length, char_used, precision, scale)
+		c.not_null = nullable == "N"
+		expr := strip_owner(strings.TrimSpace(default_expr.String), owner)
+		switch {
+		case identity == "YES":
+			c.default_expr, c.special = "GENERATED AS IDENTITY", true
+		case virtual == "YES":
+			c.default_expr, c.special = "GENERATED ALWAYS AS ("+expr+") VIRTUAL", true
+		case expr != "":
+			c.default_expr = "DEFAULT " + expr
+		}
+		cols[c.table+"."+c.column] = c
+	}
+	return cols, rows.Err()
+}
+
+func snapshot_constraints(ctx context.Context, db *sql.DB, owner string) (map[string]constraint_def, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT c.table_name, c.constraint_name, c.constraint_type, c.generated, c.status,
+		       c.search_condition_vc, c.delete_rule, c.r_owner, r.table_name,
+		       (SELECT LISTAGG('"' || cc.column_name || '"', ', ') WITHIN GROUP (ORDER BY cc.position)
+		        FROM   all_cons_columns cc
+		        WHERE  cc.owner = c.owner AND cc.constraint_name = c.constraint_name),
+		       (SELECT LISTAGG('"' || rc.column_name || '"', ', ') WITHIN GROUP (ORDER BY rc.position)
+		        FROM   all_cons_columns rc
+		        WHERE  rc.owner = c.r_owner AND rc.constraint_name = c.r_constraint_name)
+		FROM   all_constraints c
+		LEFT JOIN all_constraints r ON r.owner = c.r_owner AND r.constraint_name = c.r_constraint_name
+		WHERE  c.owner = :1
+		  AND  c.constraint_type IN ('P', 'U', 'R', 'C')
+		  AND  c.table_name NOT LIKE 'BIN$%'`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query constraints failed: %w", err)
+	}
+	defer rows.Close()
+
+	cons := map[string]constraint_def{}
+	for rows.Next() {
+		var c constraint_def
+		var kind, generated, status string
+		var cond, delete_rule, r_owner, r_table, cols, r_cols sql.NullString
+		if err := rows.Scan(&c.table, &c.name, &kind, &generated, &status, &cond, &delete_rule,
+			&r_owner, &r_table, &cols, &r_cols); err != nil {
+			return nil, err
+		}
+		switch kind {
+		case "P":
+			c.def = "PRIMARY KEY (" + cols.String + ")"
+		case "U":
+			c.def = "UNIQUE (" + cols.String + ")"
+		case "R":
+			ref := quote_identifier(r_table.String)
+			if r_owner.String != owner {
+				ref = quote_identifier(r_owner.String) + "." + ref
+			}
+			c.def = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", cols.String, ref, r_cols.String)
+			if delete_rule.String == "CASCADE" || delete_rule.String == "SET NULL" {
+				c.def += " ON DELETE " + delete_rule.String
+			}
+			c.referential = true
+		case "C":
+			if generated == "GENERATED NAME" && not_null_check_re.MatchString(cond.String) {
+				continue // covered by the column's NOT NULL
+			}
+			c.def = "CHECK (" + strip_owner(cond.String, owner) + ")"
+		}
+		if status == "DISABLED" {
+			c.def += " DISABLE"
+		}
+		key := c.table + " " + c.name
+		if generated == "GENERATED NAME" {
+			key = c.table + " " + c.def
+			c.name = ""
+		}
+		cons[key] = c
+	}
+	return cons, rows.Err()
+}
+
+func snapshot_indexes(ctx context.Context, db *sql.DB, owner string) (map[string]index_def, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT i.table_name, i.index_name, i.generated, i.uniqueness, i.index_type,
+		       (SELECT LISTAGG('"' || ic.column_name || '"' || DECODE(ic.descend, 'DESC', ' DESC'), ', ')
+		               WITHIN GROUP (ORDER BY ic.column_position)
+		        FROM   all_ind_columns ic
+		        WHERE  ic.index_owner = i.owner AND ic.index_name = i.index_name),
+		       (SELECT COUNT(*) FROM all_constraints c
+		        WHERE  c.owner = i.table_owner AND c.index_name = i.index_name)
+		FROM   all_indexes i
+		WHERE  i.owner = :1
+		  AND  i.index_type NOT IN ('LOB', 'IOT - TOP')
+		  AND  i.table_name NOT LIKE 'BIN$%'`, owner)
+	if err != nil {
+		return nil, fmt.Errorf("query indexes failed: %w", err)
+	}
+	defer rows.Close()
+
+	idx := map[string]index_def{}
+	for rows.Next() {
+		var i index_def
+		var generated, uniqueness, index_type string
+		var cols sql.NullString
+		var backing int
+		if err := rows.Scan(&i.table, &i.name, &generated, &uniqueness, &index_type, &cols, &backing); err != nil {
+			return nil, err
+		}
+		i.def = fmt.Sprintf("%s %s (%s)", uniqueness, index_type, cols.String)
+		i.generated = generated == "Y"
+		i.constraint = backing > 0
+		key := i.name
+		if i.generated {
+			key = i.table + " " + i.def
+		}
+		idx[key] = i
+	}
+	return idx, rows.Err()
+}
+
+// snapshot_grants reads the object grants on the schema's objects and the
+// object grants, roles and system privileges the schema holds, by category.
+// The DBA_ views need a DBA session; a category that cannot be read is
+// missing from the result and not compared.
+func snapshot_grants(ctx context.Context, db *sql.DB, owner string) (map[string]map[string]string, error) {
+	grants := map[string]map[string]string{}
+	option := func(yes string, clause string) string {
+		if yes == "YES" {
+			return clause
+		}
+		return ""
+	}
+
+	queries := []struct {
+		label, q string
+		key      func(a, b, c string) string
+		clause   string
+	}{
+		{"object grants given", `
+			SELECT privilege, table_name, grantee, grantable
+			FROM   all_tab_privs
+			WHERE  table_schema = :1`,
+			func(priv, obj, grantee string) string {
+				return fmt.Sprintf("%s ON %s TO %s", priv, quote_identifier(obj), quote_identifier(grantee))
+			}, "WITH GRANT OPTION"},
+		{"object grants held", `
+			SELECT privilege, table_schema || '.' || table_name, grantee, grantable
+			FROM   all_tab_privs
+			WHERE  grantee = :1`,
+			func(priv, obj, _ string) string {
+				schema, name, _ := strings.Cut(obj, ".")
+				return fmt.Sprintf("%s ON %s.%s TO {owner}", priv, quote_identifier(schema), quote_identifier(name))
+			}, "WITH GRANT OPTION"},
+		{"roles", `
+			SELECT granted_role, NULL, grantee, admin_option
+			FROM   dba_role_privs
+			WHERE  grantee = :1`,
+			func(role, _, _ string) string { return quote_identifier(role) + " TO {owner}" }, "WITH ADMIN OPTION"},
+		{"system privileges", `
+			SELECT privilege, NULL, grantee, admin_option
+			FROM   dba_sys_privs
+			WHERE  grantee = :1`,
+			func(priv, _, _ string) string { return priv + " TO {owner}" }, "WITH ADMIN OPTION"},
+	}
+	for _, q := range queries {
+		rows, err := db.QueryContext(ctx, q.q, owner)
+		if err != nil {
+			if strings.Contains(err.Error(), "ORA-00942") {
+				fmt.Printf("⚠️ %s of %s not compared: %v\n", q.label, owner, err)
+				continue
+			}
+			return nil, fmt.Errorf("query %s failed: %w", q.label, err)
+		}
+		grants[q.label] = map[string]string{}
+		for rows.Next() {
+			var a, b, c sql.NullString
+			var yes string
+			if err := rows.Scan(&a, &b, &c, &yes); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			grants[q.label][q.key(a.String, b.String, c.String)] = option(yes, q.clause)
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return grants, nil
+}
+
+// map_diff compares two maps of comparable definitions and returns the
+// sorted keys only on the left, only on the right, and on both with
+// different values.
+func map_diff[V comparable](left, right map[string]V) (only_left, only_right, changed []string) {
+	for k, lv := range left {
+		rv, ok := right[k]
+		switch {
+		case !ok:
+			only_left = append(only_left, k)
+		case rv != lv:
+			changed = append(changed, k)
+		}
+	}
+	for k := range right {
+		if _, ok := left[k]; !ok {
+			only_right = append(only_right, k)
+		}
+	}
+	sort.Strings(only_left)
+	sort.Strings(only_right)
+	sort.Strings(changed)
+	return
+}
+
+// line_diff returns the lines of a and b in diff form ("- ", "+ ", "  ")
+// with context lines around the changes, using a longest common
+// subsequence. Very large texts are only summarized.
+func line_diff(a, b string, context int) []string {
+	al := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	bl := strings.Split(strings.TrimRight(b, "\n"), "\n")
+	if len(al)*len(bl) > 4_000_000 {
+		return []string{fmt.Sprintf("  (%d vs %d lines, too large to diff)", len(al), len(bl))}
+	}
+	lcs := make([][]int32, len(al)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(bl)+1)
+	}
+	for i := len(al) - 1; i >= 0; i-- {
+		for j := len(bl) - 1; j >= 0; j-- {
+			if al[i] == bl[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var all []string
+	i, j := 0, 0
+	for i < len(al) || j < len(bl) {
+		switch {
+		case i < len(al) && j < len(bl) && al[i] == bl[j]:
+			all = append(all, "  "+al[i])
+			i++
+			j++
+		case i < len(al) && (j == len(bl) || lcs[i+1][j] >= lcs[i][j+1]):
+			all = append(all, "- "+al[i])
+			i++
+		default:
+			all = append(all, "+ "+bl[j])
+			j++
+		}
+	}
+
+	keep := make([]bool, len(all))
+	for k, l := range all {
+		if l[0] != ' ' {
+			for c := max(0, k-context); c <= min(len(all)-1, k+context); c++ {
+				keep[c] = true
+			}
+		}
+	}
+	var out []string
+	skipped := false
+	for k, l := range all {
+		if !keep[k] {
+			skipped = true
+			continue
+		}
+		if skipped && len(out) > 0 {
+			out = append(out, "  ...")
+		}
+		skipped = false
+		out = append(out, l)
+	}
+	return out
+}
+
+// sync_script collects the statements that make the right schema match the
+// left one, in execution order; destructive statements are commented out.
+type sync_script struct {
+	sections map[int][]string
+}
+
+const (
+	sync_sequences = iota
+	sync_tables
+	sync_columns
+	sync_constraints
+	sync_ref_constraints
+	sync_indexes
+	sync_code
+	sync_grants
+	sync_compile
+	sync_drops
+)
+
+func (s *sync_script) add(section int, stmt string) {
+	if s.sections == nil {
+		s.sections = map[int][]string{}
+	}
+	s.sections[section] = append(s.sections[section], strings.TrimRight(stmt, "\n"))
+}
+
+func (s *sync_script) text(left, right schema_target) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- sync %s to match %s; review before running\n", right, left)
+	fmt.Fprintf(&b, "ALTER SESSION SET CURRENT_SCHEMA = %s;\n", right.owner)
+	for section := sync_sequences; section <= sync_drops; section++ {
+		if section == sync_drops && len(s.sections[section]) > 0 {
+			b.WriteString("\n-- only in the target; uncomment to remove\n")
+		}
+		for _, stmt := range s.sections[section] {
+			b.WriteString("\n" + stmt + "\n")
+		}
+	}
+	return b.String()
+}
+
+// replaceable_types can be brought in line with CREATE OR REPLACE.
+var replaceable_types = map[string]bool{
+	"VIEW": true, "SYNONYM": true, "FUNCTION": true, "PROCEDURE": true, "PACKAGE": true,
+	"PACKAGE BODY": true, "TYPE": true, "TYPE BODY": true, "TRIGGER": true, "JAVA SOURCE": true,
+	"MLE MODULE": true,
+}
+
+// diff_report prints the differences of every category and fills the sync
+// script. It returns the number of differences.
+func diff_report(l, r *schema_snapshot, sync *sync_script) int {
+	total := 0
+	section := func(title string, n int) {
+		if n == 0 {
+			fmt.Printf("\n== %s: no differences\n", title)
+		} else {
+			fmt.Printf("\n== %s: %d difference(s)\n", title, n)
+		}
+		total += n
+	}
+	right_table := func(table string) bool {
+		_, ok := r.objects["TABLE "+table]
+		return ok
+	}
+
+	// objects and statuses
+	only_l, only_r, _ := map_diff(l.objects, r.objects)
+	_, _, status_diff := map_diff(l.status, r.status)
+	section("objects", len(only_l)+len(only_r)+len(status_diff))
+	for _, k := range only_l {
+		fmt.Printf("  - only in %s: %s\n", l.target, k)
+		o := l.objects[k]
+		ddl, ok := l.ddl[k]
+		switch {
+		case o.obj_type == "INDEX":
+			// created from the index definition below
+		case !ok:
+			sync.add(sync_code, fmt.Sprintf("-- %s: no DDL captured", k))
+		case o.obj_type == "SEQUENCE":
+			sync.add(sync_sequences, ddl)
+		case o.obj_type == "TABLE":
+			sync.add(sync_tables, ddl)
+		default:
+			sync.add(sync_code, ddl)
+		}
+	}
+	for _, k := range only_r {
+		fmt.Printf("  + only in %s: %s\n", r.target, k)
+		if o := r.objects[k]; o.obj_type != "INDEX" { // dropped with the indexes below
+			sync.add(sync_drops, fmt.Sprintf("-- DROP %s %s;", o.obj_type, quote_identifier(o.name)))
+		}
+	}
+	for _, k := range status_diff {
+		fmt.Printf("  ~ %s: %s -> %s\n", k, l.status[k], r.status[k])
+		if r.status[k] == "INVALID" && l.status[k] == "VALID" {
+			o := r.objects[k]
+			o.owner = r.target.owner
+			sync.add(sync_compile, compile_statement(o, "")+";")
+		}
+	}
+
+	// DDL
+	if l.ddl != nil && r.ddl != nil {
+		var changed []string
+		for k, ld := range l.ddl {
+			if rd, ok := r.ddl[k]; ok && rd != ld {
+				changed = append(changed, k)
+			}
+		}
+		sort.Strings(changed)
+		section("DDL", len(changed))
+		for _, k := range changed {
+			fmt.Printf("  ~ %s\n", k)
+			for _, line := range line_diff(l.ddl[k], r.ddl[k], 2) {
+				fmt.Printf("      %s\n", line)
+			}
+			o := l.objects[k]
+			switch {
+			case replaceable_types[o.obj_type]:
+				sync.add(sync_code, l.ddl[k])
+			case o.obj_type == "TABLE" || o.obj_type == "INDEX":
+				// columns, constraints and indexes are synced below
+			default:
+				sync.add(sync_code, fmt.Sprintf("-- %s differs; recreate it by hand", k))
+			}
+		}
+	}
+
+	// columns of tables on both sides
+	only_l, only_r, changed := map_diff(l.columns, r.columns)
+	var col_diffs []string
+	for _, k := range only_l {
+		c := l.columns[k]
+		if !right_table(c.table) {
+			continue
+		}
+		col_diffs = append(col_diffs, fmt.Sprintf("  - only in %s: %s %s", l.target, k, c))
+		if c.special {
+			sync.add(sync_columns, fmt.Sprintf("-- add %s %s by hand", k, c))
+			continue
+		}
+		sync.add(sync_columns, fmt.Sprintf("ALTER TABLE %s ADD (%s %s);", quote_identifier(c.table), quote_identifier(c.column), c))
+	}
+	for _, k := range only_r {
+		c := r.columns[k]
+		if _, ok := l.objects["TABLE "+c.table]; !ok {
+			continue
+		}
+		col_diffs = append(col_diffs, fmt.Sprintf("  + only in %s: %s %s", r.target, k, c))
+		sync.add(sync_drops, fmt.Sprintf("-- ALTER TABLE %s DROP COLUMN %s;", quote_identifier(c.table), quote_identifier(c.column)))
+	}
+	for _, k := range changed {
+		lc, rc := l.columns[k], r.columns[k]
+		col_diffs = append(col_diffs, fmt.Sprintf("  ~ %s: %s -> %s", k, lc, rc))
+		target := quote_identifier(lc.table)
+		if lc.special || rc.special {
+			sync.add(sync_columns, fmt.Sprintf("-- change %s to %s by hand", k, lc))
+			continue
+		}
+		if lc.data_type != rc.data_type || lc.default_expr != rc.default_expr {
+			def := lc.data_type
+			if lc.default_expr != "" {
+				def += " " + lc.default_expr
+			} else if rc.default_expr != "" {
+				def += " DEFAULT NULL"
+			}
+			sync.add(sync_columns, fmt.Sprintf("ALTER TABLE %s MODIFY (%s %s);", target, quote_identifier(lc.column), def))
+		}
+		if lc.not_null != rc.not_null {
+			null := "NULL"
+			if lc.not_null {
+				null = "NOT NULL"
+			}
+			sync.add(sync_columns, fmt.Sprintf("ALTER TABLE %s MODIFY (%s %s);", target, quote_identifier(lc.column), null))
+		}
+	}
+	section("columns", len(col_diffs))
+	for _, d := range col_diffs {
+		fmt.Println(d)
+	}
+
+	// constraints of tables on both sides
+	only_l, only_r, changed = map_diff(l.constraints, r.constraints)
+	var con_diffs []string
+	add_constraint := func(c constraint_def) {
+		named := ""
+		if c.name != "" {
+			named = "CONSTRAINT " + quote_identifier(c.name) + " "
+		}
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD %s%s;", quote_identifier(c.table), named, c.def)
+		if c.referential {
+			sync.add(sync_ref_constraints, stmt)
+		} else {
+			sync.add(sync_constraints, stmt)
+		}
+	}
+	drop_constraint := func(c constraint_def, section int, prefix string) {
+		name := c.name
+		if name == "" {
+			sync.add(section, fmt.Sprintf("-- drop the system-named %s on %s by hand", c.def, c.table))
+			return
+		}
+		sync.add(section, fmt.Sprintf("%sALTER TABLE %s DROP CONSTRAINT %s;", prefix, quote_identifier(c.table), quote_identifier(name)))
+	}
+	for _, k := range only_l {
+		c := l.constraints[k]
+		if !right_table(c.table) {
+			// a new table: its DDL has every constraint but the foreign keys
+			if c.referential {
+				add_constraint(c)
+			}
+			continue
+		}
+		con_diffs = append(con_diffs, fmt.Sprintf("  - only in %s: %s %s", l.target, k, c.def))
+		add_constraint(c)
+	}
+	for _, k := range only_r {
+		c := r.constraints[k]
+		if _, ok := l.objects["TABLE "+c.table]; !ok {
+			continue
+		}
+		con_diffs = append(con_diffs, fmt.Sprintf("  + only in %s: %s %s", r.target, k, c.def))
+		drop_constraint(c, sync_drops, "-- ")
+	}
+	for _, k := range changed {
+		con_diffs = append(con_diffs, fmt.Sprintf("  ~ %s: %s -> %s", k, l.constraints[k].def, r.constraints[k].def))
+		drop_constraint(r.constraints[k], sync_constraints, "")
+		add_constraint(l.constraints[k])
+	}
+	section("constraints", len(con_diffs))
+	for _, d := range con_diffs {
+		fmt.Println(d)
+	}
+
+	// indexes
+	only_l, only_r, changed = map_diff(l.indexes, r.indexes)
+	create_index := func(i index_def) {
+		if i.constraint || i.generated {
+			return // comes with its constraint
+		}
+		kind := ""
+		uniqueness, rest, _ := strings.Cut(i.def, " ")
+		if uniqueness == "UNIQUE" {
+			kind = "UNIQUE "
+		}
+		if strings.HasPrefix(rest, "BITMAP (") {
+			kind = "BITMAP "
+		}
+		if !strings.HasPrefix(rest, "NORMAL (") && !strings.HasPrefix(rest, "BITMAP (") {
+			if ddl, ok := l.ddl["INDEX "+i.name]; ok {
+				sync.add(sync_indexes, ddl)
+			} else {
+				sync.add(sync_indexes, fmt.Sprintf("-- create %s index %s by hand", rest, i.name))
+			}
+			return
+		}
+		cols := rest[strings.Index(rest, "("):]
+		sync.add(sync_indexes, fmt.Sprintf("CREATE %sINDEX %s ON %s %s;", kind, quote_identifier(i.name), quote_identifier(i.table), cols))
+	}
+	var idx_diffs []string
+	for _, k := range only_l {
+		i := l.indexes[k]
+		idx_diffs = append(idx_diffs, fmt.Sprintf("  - only in %s: %s ON %s %s", l.target, i.name, i.table, i.def))
+		create_index(i)
+	}
+	for _, k := range only_r {
+		i := r.indexes[k]
+		idx_diffs = append(idx_diffs, fmt.Sprintf("  + only in %s: %s ON %s %s", r.target, i.name, i.table, i.def))
+		if !i.constraint && !i.generated {
+			sync.add(sync_drops, fmt.Sprintf("-- DROP INDEX %s;", quote_identifier(i.name)))
+		}
+	}
+	for _, k := range changed {
+		li, ri := l.indexes[k], r.indexes[k]
+		idx_diffs = append(idx_diffs, fmt.Sprintf("  ~ %s: %s ON %s -> %s ON %s", k, li.def, li.table, ri.def, ri.table))
+		if !ri.constraint && !ri.generated && !li.constraint {
+			sync.add(sync_indexes, fmt.Sprintf("DROP INDEX %s;", quote_identifier(ri.name)))
+			create_index(li)
+		}
+	}
+	section("indexes", len(idx_diffs))
+	for _, d := range idx_diffs {
+		fmt.Println(d)
+	}
+
+	// grants, by category readable on both sides
+	render := func(g string, t schema_target) string {
+		return strings.ReplaceAll(g, "{owner}", quote_identifier(t.owner))
+	}
+	var categories []string
+	for c := range l.grants {
+		if _, ok := r.grants[c]; ok {
+			categories = append(categories, c)
+		}
+	}
+	sort.Strings(categories)
+	for _, c := range categories {
+		lg, rg := l.grants[c], r.grants[c]
+		only_l, only_r, changed = map_diff(lg, rg)
+		section(c, len(only_l)+len(only_r)+len(changed))
+		revoke := func(g string) string {
+			i := strings.LastIndex(g, " TO ")
+			return "REVOKE " + render(g[:i], r.target) + " FROM " + render(g[i+len(" TO "):], r.target) + ";"
+		}
+		for _, g := range append(only_l, changed...) {
+			fmt.Printf("  - in %s: %s\n", l.target, strings.TrimSpace("GRANT "+render(g, l.target)+" "+lg[g]))
+			if lg[g] == "" && rg[g] != "" {
+				// a grant cannot drop its own WITH GRANT/ADMIN OPTION
+				sync.add(sync_grants, revoke(g))
+			}
+			stmt := "GRANT " + render(g, r.target)
+			if lg[g] != "" {
+				stmt += " " + lg[g]
+			}
+			sync.add(sync_grants, stmt+";")
+		}
+		for _, g := range only_r {
+			fmt.Printf("  + only in %s: %s\n", r.target, strings.TrimSpace("GRANT "+render(g, r.target)+" "+rg[g]))
+			sync.add(sync_drops, "-- "+revoke(g))
+		}
+	}
+	return total
+}
+
+func run_schema(args []string) error {
+	return run_group("schema", []command{
+		{"diff", "compare two schemas ([profile:][container/]owner) and optionally write a sync script", run_schema_diff},
+	}, args)
+}
+
+func run_schema_diff(args []string) error {
+	fs := flag.NewFlagSet("schema diff", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	sync_path := fs.String("sync", "", "write a script that makes the right schema match the left one")
+	no_ddl := fs.Bool("no-ddl", false, "do not fetch and compare DBMS_METADATA DDL")
+	exit_code := fs.Bool("exit-code", false, "fail when the schemas differ")
+	positional := parse_interspersed(fs, args)
+	if len(positional) != 2 {
+		return fmt.Errorf("usage: schema diff [flags] <left> <right>, each [profile:][container/]owner")
+	}
+
+	ctx := context.Background()
+	var snaps []*schema_snapshot
+	for _, arg := range positional {
+		t, err := parse_schema_target(arg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", arg, err)
+		}
+		db, err := t.open(ctx, cf)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("🔎 reading %s\n", t)
+		s, err := take_snapshot(ctx, db, t, !*no_ddl)
+		db.Close()
+		if err != nil {
+			return err
+		}
+		snaps = append(snaps, s)
+	}
+
+	sync := &sync_script{}
+	n := diff_report(snaps[0], snaps[1], sync)
+	fmt.Printf("\n📊 %d difference(s) between %s and %s\n", n, snaps[0].target, snaps[1].target)
+
+	if *sync_path != "" {
+		if err := os.WriteFile(*sync_path, []byte(sync.text(snaps[0].target, snaps[1].target)), 0o644); err != nil {
+			return err
+		}
+		fmt.Printf("📝 sync script written to %s\n", *sync_path)
+	}
+	if *exit_code && n > 0 {
+		return fmt.Errorf("schemas differ")
+	}
+	return nil
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const default_script_corpus = "testdata/sqlplus"
+
+// define_flags collects repeated -define name=value flags.
+type define_flags map[string]string
+
+func (d define_flags) String() string { return fmt.Sprint(map[string]string(d)) }
+
+func (d define_flags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	d[strings.ToUpper(strings.TrimSpace(name))] = value
+	return nil
+}
+
+func add_define_flags(fs *flag.FlagSet) define_flags {
+	d := define_flags{}
+	fs.Var(d, "define", "substitution variable name=value for &name (repeatable)")
+	return d
+}
+
+// script_runner executes parsed script items on one session. CREATE
+// statements go through deploy_ddl so their status is verified and compile
+// errors are printed; everything else is executed as is.
+type script_runner struct {
+	db            *sql.DB
+	owner         string
+	file          string          // script path, for mapping compile errors
+	lines         []string        // script content
+	exit_on_error bool            // WHENEVER SQLERROR EXIT
+	output        *output_capture // SET SERVEROUTPUT ON or -serveroutput
+	exited        bool            // EXIT or QUIT was reached
+	ok_count      int
+	fail_count    int
+	diagnostics   []compile_error // compile errors of INVALID objects
+}
+
+// run executes items; label prefixes error messages (usually the file).
+// With exit_on_error the first failure is returned, otherwise it is printed
+// and the script continues, as SQL*Plus does by default.
+func (r *script_runner) run(ctx context.Context, label string, items []script_item) error {
+	for _, it := range items {
+		if r.exited {
+			return nil
+		}
+		where := label
+		if it.line > 0 {
+			where = fmt.Sprintf("%s line %d", label, it.line)
+		}
+		if it.kind == item_command {
+			if err := r.command(ctx, it); err != nil {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			continue
+		}
+
+		err := r.exec(ctx, it)
+		if out_err := r.output.collect(ctx, r.db, where); out_err != nil && err == nil {
+			err = out_err
+		}
+		if err != nil {
+			r.fail_count++
+			var invalid *invalid_object_error
+			if errors.As(err, &invalid) {
+				r.diagnostics = append(r.diagnostics, invalid.errors...)
+			}
+			if r.exit_on_error {
+				return fmt.Errorf("%s: %w", where, err)
+			}
+			fmt.Printf("❌ %s: %v\n", where, err)
+			continue
+		}
+		r.ok_count++
+	}
+	return nil
+}
+
+var query_start_re = regexp.MustCompile(`(?i)^(SELECT|WITH)\b`)
+
+func (r *script_runner) exec(ctx context.Context, it script_item) error {
+	text := strip_leading_comments(it.text)
+	if _, err := parse_object_ddl(text); err == nil {
+		var loc *source_location
+		if r.file != "" {
+			loc = statement_location(r.file, r.lines, it.line, it.text)
+		}
+		_, err := deploy_ddl(ctx, r.db, r.owner, it.text, loc)
+		return err
+	}
+	if query_start_re.MatchString(text) {
+		// A WITH FUNCTION query is a plsql item; a ";" after its final
+		// SELECT is not part of the statement.
+		query := it.text
+		if it.kind == item_plsql {
+			query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+		}
+		return print_query(ctx, r.db, query)
+	}
+	if _, err := r.db.ExecContext(ctx, it.text); err != nil {
+		return fmt.Errorf("%s: %w", ddl_preview(it.text), err)
+	}
+	fmt.Printf("✅ %s\n", ddl_preview(it.text))
+	return nil
+}
+
+// command applies the SQL*Plus commands the runner understands and skips
+// the rest (formatting, spooling and so on) with a note.
+func (r *script_runner) command(ctx context.Context, it script_item) error {
+	fields := strings.Fields(it.text)
+	word := strings.ToUpper(fields[0])
+	arg := func(i int) string {
+		if i < len(fields) {
+			return strings.ToUpper(fields[i])
+		}
+		return ""
+	}
+
+	switch {
+	case word == "PROMPT":
+		fmt.Println(strings.TrimSpace(strings.TrimPrefix(it.text, fields[0])))
+		return nil
+	case word == "SET" && sqlplus_abbrev(arg(1), "SERVEROUTPUT", 9):
+		switch arg(2) {
+		case "ON":
+			if r.output == nil {
+				r.output = &output_capture{}
+			}
+			r.output.enabled = true
+			return enable_dbms_output(ctx, r.db)
+		case "OFF":
+			if r.output != nil {
+				r.output.enabled = false
+			}
+			return disable_dbms_output(ctx, r.db)
+		}
+		return fmt.Errorf("expected SET SERVEROUTPUT ON|OFF, got %q", it.text)
+	case word == "WHENEVER" && arg(1) == "SQLERROR":
+		switch {
+		case strings.HasPrefix(arg(2), "EXIT"):
+			r.exit_on_error = true
+		case strings.HasPrefix(arg(2), "CONTINUE"):
+			r.exit_on_error = false
+		default:
+			return fmt.Errorf("expected WHENEVER SQLERROR EXIT|CONTINUE, got %q", it.text)
+		}
+		return nil
+	case sqlplus_abbrev(word, "EXIT", 4) || sqlplus_abbrev(word, "QUIT", 4):
+		r.exited = true
+		return nil
+	}
+	fmt.Printf("ℹ️ line %d: skipping SQL*Plus command: %s\n", it.line, it.text)
+	return nil
+}
+
+const max_printed_rows = 100
+
+// print_query runs a query and prints up to max_printed_rows rows, tab
+// separated with a header line.
+func print_query(ctx context.Context, db *sql.DB, query string) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("%s: %w", ddl_preview(query), err)
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	fmt.Println(strings.Join(cols, "\t"))
+	vals := make([]any, len(cols))
+	ptrs := make([]any, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		n++
+		if n > max_printed_rows {
+			continue
+		}
+		cells := make([]string, len(vals))
+		for i, v := range vals {
+			if v == nil {
+				cells[i] = ""
+			} else {
+				cells[i] = fmt.Sprint(v)
+			}
+		}
+		fmt.Println(strings.Join(cells, "\t"))
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if n > max_printed_rows {
+		fmt.Printf("... %d more row(s)\n", n-max_printed_rows)
+	}
+	fmt.Printf("📊 %d row(s) selected\n", n)
+	return nil
+}
+
+// read_script parses a script file and also returns its lines, which the
+// runner uses to show compile errors in context. A .tmpl file is rendered
+// with tc before it is parsed.
+func read_script(path string, defines map[string]string, tc *template_context) ([]script_item, []string, error) {
+	text, err := read_source(path, tc)
+	if err != nil {
+		return nil, nil, err
+	}
+	items, err := parse_script(text, defines)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return items, file_lines(text), nil
+}
+
+func file_lines(text string) []string {
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// render_items prints parsed items in the format of the corpus .expected
+// files: a header line per item followed by its text.
+func render_items(items []script_item) string {
+	var b strings.Builder
+	for i, it := range items {
+		fmt.Fprintf(&b, "-- [%d] %s, line %d\n%s\n", i+1, it.kind, it.line, it.text)
+	}
+	return b.String()
+}
+
+func run_script_group(args []string) error {
+	return run_group("script", []command{
+		{"run", "run a SQL*Plus script on the target container", run_script_run},
+		{"parse", "print the statements and commands of a script without connecting", run_script_parse},
+		{"check", "parse the corpus scripts and compare with their .expected output", run_script_check},
+	}, args)
+}
+
+func run_script_run(args []string) error {
+	fs := flag.NewFlagSet("script run", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	defines := add_define_flags(fs)
+	tf := add_template_flags(fs)
+	rf := add_report_flags(fs)
+	output := add_output_flags(fs)
+	owner := fs.String("owner", "", "schema to run in (default: the session's current schema)")
+	positional := parse_interspersed(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: script run [flags] <file.sql>")
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if err := output.enable(ctx, db); err != nil {
+		return err
+	}
+	r := &script_runner{db: db, file: positional[0], output: output}
+	if *owner != "" {
+		if r.owner, err = check_identifier("owner", *owner); err != nil {
+			return err
+		}
+		if _, err := db.ExecContext(ctx, "ALTER SESSION SET CURRENT_SCHEMA = "+r.owner); err != nil {
+			return fmt.Errorf("set current_schema failed: %w", err)
+		}
+	} else if err := db.QueryRowContext(ctx,
+		"SELECT SYS_CONTEXT('USERENV','CURRENT_SCHEMA') FROM dual").Scan(&r.owner); err != nil {
+		return fmt.Errorf("could not read current schema: %w", err)
+	}
+
+	tc, err := tf.context(cf, r.owner)
+	if err != nil {
+		return err
+	}
+	if err := tc.connect(ctx, db); err != nil {
+		return err
+	}
+	items, lines, err := read_script(positional[0], defines, tc)
+	if err != nil {
+		return err
+	}
+	r.lines = lines
+	err = r.run(ctx, positional[0], items)
+	fmt.Printf("📊 statements OK=%d, failed=%d\n", r.ok_count, r.fail_count)
+	if report_err := rf.write(r.diagnostics); report_err != nil && err == nil {
+		err = report_err
+	}
+	return err
+}
+
+func run_script_parse(args []string) error {
+	fs := flag.NewFlagSet("script parse", flag.ExitOnError)
+	cf := add_conn_flags(fs) // -config and -container for templates; nothing connects
+	defines := add_define_flags(fs)
+	tf := add_template_flags(fs)
+	owner := fs.String("owner", "", "schema a template sees as {{.Username}}")
+	positional := parse_interspersed(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: script parse [flags] <file.sql>")
+	}
+	tc, err := tf.context(cf, strings.ToUpper(*owner))
+	if err != nil {
+		return err
+	}
+	items, _, err := read_script(positional[0], defines, tc)
+	if err != nil {
+		return err
+	}
+	fmt.Print(render_items(items))
+	return nil
+}
+
+// script_corpus is the parser corpus: each <name>.sql is parsed without
+// defines and its items (or the parse error) are compared with
+// <name>.expected. TestParseScriptCorpus runs the same comparison under
+// go test.
+var script_corpus = corpus_spec{
+	what:    "script",
+	dir:     default_script_corpus,
+	globs:   []string{"*.sql"},
+	out_ext: ".expected",
+	render: func(path string) (string, error) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		items, err := parse_script(string(data), nil)
+		if err != nil {
+			return "", err
+		}
+		return render_items(items), nil
+	},
+}
+
+func run_script_check(args []string) error {
+	return run_corpus_check("script check", script_corpus, args)
+}
@@ -0,0 +1,484 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SQL*Plus script parsing.
+//
+// A script is read line by line the way SQL*Plus reads it:
+//   - SQL statements end at a ";" outside strings and comments, or at a line
+//     holding a single "/".
+//   - PL/SQL blocks (DECLARE, BEGIN, CREATE FUNCTION/PROCEDURE/PACKAGE/TYPE/
+//     TRIGGER/LIBRARY, CREATE JAVA, CREATE MLE MODULE, and queries with
+//     WITH FUNCTION/PROCEDURE) keep their ";" and end only at a "/" line. As
+//     in SQL*Plus that line ends the block even inside an apparent string,
+//     so an apostrophe in a comment or in Java source cannot swallow it. A "." line ends input without running the block.
+//   - A "/" line with nothing buffered runs the previous statement again.
+//   - "--" and "/* */" comments, '...' strings with '' escapes, "..."
+//     identifiers and q'[...]' quotes are honoured when looking for ";".
+//   - &var and &&var are replaced from DEFINEs before a line is parsed
+//     ("&var." eats the period); SET DEFINE OFF disables substitution.
+//   - SQL*Plus commands (PROMPT, SET, WHENEVER, EXIT, ...) are recognised only
+//     at the start of a statement and take the rest of the line.
+//
+// Blank lines do not end a SQL statement (as with SET SQLBLANKLINES ON), and
+// a statement still open at the end of the script is returned as if it had
+// been terminated.
+
+const (
+	item_sql     = "sql"
+	item_plsql   = "plsql"
+	item_command = "command"
+)
+
+// script_item is one statement or SQL*Plus command of a script.
+type script_item struct {
+	kind string // item_sql, item_plsql or item_command
+	text string // statement without its terminator, or the command line
+	line int    // 1-based line of the first token
+}
+
+// script_parser holds the state carried from line to line.
+type script_parser struct {
+	defines   map[string]string
+	define_on bool
+	items     []script_item
+
+	buf      strings.Builder
+	buf_line int
+	mode     string // "", item_sql or item_plsql
+	last     *script_item
+
+	in_quote      byte // '\'' or '"' while inside a string or quoted identifier
+	q_close       byte // closing delimiter while inside a q'..' quote
+	in_block_comm bool
+}
+
+// parse_script splits a SQL*Plus script into statements and commands,
+// applying substitution variables. defines may be nil; DEFINE commands in
+// the script add to it.
+func parse_script(text string, defines map[string]string) ([]script_item, error) {
+	p := &script_parser{defines: map[string]string{}, define_on: true}
+	for k, v := range defines {
+		p.defines[strings.ToUpper(k)] = v
+	}
+
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if err := p.feed(line, i+1); err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+	}
+	p.flush()
+	return p.items, nil
+}
+
+// statements returns only the SQL and PL/SQL items.
+func statements(items []script_item) []script_item {
+	var out []script_item
+	for _, it := range items {
+		if it.kind != item_command {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+var subst_re = regexp.MustCompile(`&(&?)([A-Za-z0-9_$#]+)(\.?)`)
+
+func (p *script_parser) substitute(line string) (string, error) {
+	var missing string
+	out := subst_re.ReplaceAllStringFunc(line, func(m string) string {
+		sm := subst_re.FindStringSubmatch(m)
+		name := strings.ToUpper(sm[2])
+		v, ok := p.defines[name]
+		if !ok {
+			if missing == "" {
+				missing = sm[2]
+			}
+			return m
+		}
+		return v
+	})
+	if missing != "" {
+		return "", fmt.Errorf("substitution variable &%s is not defined", missing)
+	}
+	return out, nil
+}
+
+func (p *script_parser) emit(kind, text string, line int) {
+	it := script_item{kind: kind, text: text, line: line}
+	p.items = append(p.items, it)
+	if kind != item_command {
+		p.last = &it
+	}
+}
+
+func (p *script_parser) reset() {
+	p.buf.Reset()
+	p.mode = ""
+	p.buf_line = 0
+}
+
+// flush emits a statement still open at the end of the script.
+func (p *script_parser) flush() {
+	text := strings.TrimSpace(p.buf.String())
+	if p.mode != "" && strip_leading_comments(text) != "" {
+		p.emit(p.mode, text, p.buf_line)
+	}
+	p.reset()
+}
+
+func (p *script_parser) feed(line string, n int) error {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "/" && p.mode == item_plsql {
+		p.in_quote, p.q_close, p.in_block_comm = 0, 0, false
+		p.flush()
+		return nil
+	}
+	no_quote := p.in_quote == 0 && p.q_close == 0
+
+	// "/" and "." lines
+	if no_quote && !(p.mode == "" && p.in_block_comm) {
+		if trimmed == "/" {
+			if p.mode == "" {
+				if p.last != nil {
+					p.emit(p.last.kind, p.last.text, p.last.line)
+				}
+				return nil
+			}
+			p.in_block_comm = false
+			p.flush()
+			return nil
+		}
+		if trimmed == "." && p.mode == item_plsql {
+			// end input; the block becomes the buffer a later "/" runs
+			text := strings.TrimSpace(p.buf.String())
+			p.last = &script_item{kind: item_plsql, text: text, line: p.buf_line}
+			p.reset()
+			return nil
+		}
+	}
+
+	if p.mode == "" && !p.in_block_comm {
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			return nil
+		}
+		if handled, err := p.command(trimmed, n); handled || err != nil {
+			return err
+		}
+	}
+
+	if p.define_on && !strings.HasPrefix(trimmed, "--") {
+		var err error
+		if line, err = p.substitute(line); err != nil {
+			return err
+		}
+	}
+
+	if p.mode == "" {
+		if p.in_block_comm || strings.HasPrefix(trimmed, "/*") {
+			// comment-only lines before a statement
+			rest := p.skip_comments(line)
+			if strings.TrimSpace(rest) == "" {
+				return nil
+			}
+			line = rest
+		}
+		p.buf_line = n
+		p.mode = item_sql
+	}
+
+	if p.mode == item_sql && plsql_prefix_only(p.buf.String()) {
+		if is_plsql_start(p.buf.String() + " " + line) {
+			p.mode = item_plsql
+		}
+	}
+
+	if p.mode == item_plsql {
+		p.scan(line, false)
+		p.buf.WriteString(line)
+		p.buf.WriteByte('\n')
+		return nil
+	}
+
+	// SQL: look for ";" terminators
+	for {
+		end := p.scan(line, true)
+		if end < 0 {
+			p.buf.WriteString(line)
+			p.buf.WriteByte('\n')
+			return nil
+		}
+		p.buf.WriteString(line[:end])
+		p.flush()
+		line = line[end+1:]
+		if strings.TrimSpace(line) == "" {
+			return nil
+		}
+		// more text after ";" on the same line starts a new statement
+		p.mode = item_sql
+		p.buf_line = n
+	}
+}
+
+// skip_comments drops a leading block comment (possibly continued from an
+// earlier line) and returns the remainder of the line.
+func (p *script_parser) skip_comments(line string) string {
+	for {
+		if p.in_block_comm {
+			i := strings.Index(line, "*/")
+			if i < 0 {
+				return ""
+			}
+			p.in_block_comm = false
+			line = line[i+2:]
+			continue
+		}
+		t := strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(t, "/*") {
+			p.in_block_comm = true
+			line = t[2:]
+			continue
+		}
+		if strings.HasPrefix(t, "--") {
+			return ""
+		}
+		return line
+	}
+}
+
+// scan advances the lexer over one line. With want_semicolon it returns the
+// byte offset of the first ";" outside strings and comments, else -1.
+func (p *script_parser) scan(line string, want_semicolon bool) int {
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case p.in_block_comm:
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				p.in_block_comm = false
+				i++
+			}
+		case p.q_close != 0:
+			if c == p.q_close && i+1 < len(line) && line[i+1] == '\'' {
+				p.q_close = 0
+				i++
+			}
+		case p.in_quote != 0:
+			if c == p.in_quote {
+				if i+1 < len(line) && line[i+1] == p.in_quote {
+					i++ // doubled quote
+				} else {
+					p.in_quote = 0
+				}
+			}
+		case c == '-' && i+1 < len(line) && line[i+1] == '-':
+			return -1
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			p.in_block_comm = true
+			i++
+		case c == '\'' && is_q_quote_start(line, i) && i+1 < len(line):
+			p.q_close = q_closing(line[i+1])
+			i++
+		case c == '\'' || c == '"':
+			p.in_quote = c
+		case c == ';' && want_semicolon:
+			return i
+		}
+	}
+	return -1
+}
+
+// is_q_quote_start reports whether the quote at i opens a q-quote such as
+// q'[...]' or nq'{...}'.
+func is_q_quote_start(line string, i int) bool {
+	if i == 0 || (line[i-1] != 'q' && line[i-1] != 'Q') {
+		return false
+	}
+	if i >= 2 {
+		b := line[i-2]
+		if b == 'n' || b == 'N' {
+			return i < 3 || !is_ident_byte(line[i-3])
+		}
+		return !is_ident_byte(b)
+	}
+	return true
+}
+
+func q_closing(open byte) byte {
+	switch open {
+	case '[':
+		return ']'
+	case '{':
+		return '}'
+	case '(':
+		return ')'
+	case '<':
+		return '>'
+	}
+	return open
+}
+
+func is_ident_byte(c byte) bool {
+	return c == '_' || c == '$' || c == '#' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+var plsql_start_re = regexp.MustCompile(`(?is)^(DECLARE|BEGIN)\b|^WITH\s+(FUNCTION|PROCEDURE)\b|^CREATE\s+(OR\s+REPLACE\s+)?` +
+	`((NON)?EDITIONABLE\s+)?(AND\s+(RESOLVE|COMPILE)\s+)?(NOFORCE\s+)?` +
+	`(FUNCTION|PROCEDURE|PACKAGE|TYPE|TRIGGER|LIBRARY|JAVA|MLE\s+MODULE)\b`)
+
+var plsql_prefix_words = map[string]bool{
+	"CREATE": true, "OR": true, "REPLACE": true, "EDITIONABLE": true, "NONEDITIONABLE": true,
+	"AND": true, "RESOLVE": true, "COMPILE": true, "NOFORCE": true, "MLE": true,
+	"WITH": true,
+}
+
+// plsql_prefix_only reports whether the text so far could still turn into a
+// PL/SQL block once the next line arrives ("CREATE OR REPLACE" or "WITH" +
+// newline).
+func plsql_prefix_only(text string) bool {
+	words := strings.Fields(strip_leading_comments(text))
+	if len(words) == 0 {
+		return true
+	}
+	for _, w := range words {
+		if !plsql_prefix_words[strings.ToUpper(w)] {
+			return false
+		}
+	}
+	return true
+}
+
+func is_plsql_start(text string) bool {
+	return plsql_start_re.MatchString(strip_leading_comments(strings.TrimSpace(text)))
+}
+
+// sqlplus_abbrev reports whether word abbreviates full to at least min letters.
+func sqlplus_abbrev(word, full string, min int) bool {
+	word = strings.ToUpper(word)
+	return len(word) >= min && len(word) <= len(full) && strings.HasPrefix(full, word)
+}
+
+// sql_set_words are the SET statements that are SQL, not SQL*Plus.
+var sql_set_words = map[string]bool{"TRANSACTION": true, "ROLE": true, "CONSTRAINT": true, "CONSTRAINTS": true}
+
+// command handles a SQL*Plus command at the start of a statement. DEFINE,
+// UNDEFINE and SET DEFINE take effect while parsing; EXECUTE becomes a
+// PL/SQL block; everything else is emitted for the executor.
+func (p *script_parser) command(trimmed string, n int) (bool, error) {
+	if strings.HasPrefix(trimmed, "@") {
+		p.emit(item_command, trimmed, n)
+		return true, nil
+	}
+	fields := strings.Fields(trimmed)
+	word := strings.TrimRight(fields[0], ";")
+	rest := strings.TrimSpace(trimmed[len(fields[0]):])
+
+	switch {
+	case sqlplus_abbrev(word, "REMARK", 3):
+		return true, nil
+	case sqlplus_abbrev(word, "DEFINE", 3):
+		line := rest
+		if p.define_on {
+			var err error
+			if line, err = p.substitute(rest); err != nil {
+				return true, err
+			}
+		}
+		return true, p.define(line, n)
+	case sqlplus_abbrev(word, "UNDEFINE", 5):
+		for _, name := range strings.Fields(rest) {
+			delete(p.defines, strings.ToUpper(name))
+		}
+		return true, nil
+	case strings.EqualFold(word, "SET"):
+		if len(fields) > 1 && sql_set_words[strings.ToUpper(fields[1])] {
+			return false, nil
+		}
+		if len(fields) > 1 && sqlplus_abbrev(fields[1], "DEFINE", 3) && len(fields) > 2 {
+			p.define_on = !strings.EqualFold(strings.TrimRight(fields[2], ";"), "OFF")
+			return true, nil
+		}
+		p.emit(item_command, strings.TrimRight(trimmed, ";"), n)
+		return true, nil
+	case sqlplus_abbrev(word, "EXECUTE", 4):
+		text := rest
+		if p.define_on {
+			var err error
+			if text, err = p.substitute(rest); err != nil {
+				return true, err
+			}
+		}
+		text = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(text), ";"))
+		p.emit(item_plsql, "BEGIN "+text+"; END;", n)
+		return true, nil
+	case sqlplus_abbrev(word, "PROMPT", 3):
+		text := rest
+		if p.define_on {
+			var err error
+			if text, err = p.substitute(rest); err != nil {
+				return true, err
+			}
+		}
+		p.emit(item_command, "PROMPT "+text, n)
+		return true, nil
+	}
+
+	for _, c := range sqlplus_commands {
+		if sqlplus_abbrev(word, c.full, c.min) {
+			p.emit(item_command, strings.TrimRight(trimmed, ";"), n)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// sqlplus_commands are the remaining commands that end at the end of line.
+var sqlplus_commands = []struct {
+	full string
+	min  int
+}{
+	{"WHENEVER", 8}, {"EXIT", 4}, {"QUIT", 4}, {"SPOOL", 3}, {"SHOW", 3}, {"CLEAR", 2},
+	{"COLUMN", 3}, {"TTITLE", 3}, {"BTITLE", 3}, {"BREAK", 3}, {"COMPUTE", 4}, {"PAUSE", 3},
+	{"VARIABLE", 3}, {"PRINT", 3}, {"ACCEPT", 3}, {"CONNECT", 4}, {"DISCONNECT", 4},
+	{"HOST", 2}, {"START", 3}, {"DESCRIBE", 4}, {"TIMING", 3}, {"STORE", 3}, {"REPHEADER", 4},
+	{"REPFOOTER", 4}, {"ARCHIVE", 7}, {"PASSWORD", 4}, {"RECOVER", 7}, {"STARTUP", 7}, {"SHUTDOWN", 8},
+}
+
+var define_re = regexp.MustCompile(`^([A-Za-z0-9_$#]+)\s*=\s*(.*)$`)
+
+func (p *script_parser) define(rest string, n int) error {
+	rest = strings.TrimSpace(rest)
+	if rest == "" || define_re.FindStringSubmatch(rest) == nil {
+		// DEFINE / DEFINE name: list the variables
+		names := make([]string, 0, len(p.defines))
+		for k := range p.defines {
+			if rest == "" || strings.EqualFold(k, rest) {
+				names = append(names, k)
+			}
+		}
+		sort.Strings(names)
+		for _, k := range names {
+			p.emit(item_command, fmt.Sprintf("PROMPT DEFINE %s = %q", k, p.defines[k]), n)
+		}
+		return nil
+	}
+	m := define_re.FindStringSubmatch(rest)
+	value := strings.TrimSpace(m[2])
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') {
+		q := value[0]
+		end := strings.IndexByte(value[1:], q)
+		if end < 0 {
+			return fmt.Errorf("DEFINE %s: unterminated quoted value", m[1])
+		}
+		value = value[1 : end+1]
+	} else if f := strings.Fields(value); len(f) > 0 {
+		value = f[0]
+	}
+	p.defines[strings.ToUpper(m[1])] = value
+	return nil
+}
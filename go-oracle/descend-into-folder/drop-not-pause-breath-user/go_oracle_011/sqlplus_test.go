@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+// TestParseScriptCorpus parses every <name>.sql of testdata/sqlplus and
+// compares the rendered items (or the parse error) with <name>.expected.
+func TestParseScriptCorpus(t *testing.T) {
+	test_corpus(t, script_corpus)
+}
@@ -0,0 +1,457 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var size_re = regexp.MustCompile(`^(?i)\d+[KMGT]?$`)
+
+func check_size(flag_name, s string, unlimited_ok bool) (string, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if unlimited_ok && s == "UNLIMITED" {
+		return s, nil
+	}
+	if !size_re.MatchString(s) {
+		return "", fmt.Errorf("invalid -%s %q (want e.g. 500M or 2G)", flag_name, s)
+	}
+	return s, nil
+}
+
+// tablespace_flags are shared by the tablespace subcommands.
+type tablespace_flags struct {
+	name    string
+	dir     string
+	dry_run bool
+}
+
+func add_tablespace_flags(fs *flag.FlagSet) *tablespace_flags {
+	tf := &tablespace_flags{}
+	fs.StringVar(&tf.name, "name", "", "tablespace name")
+	fs.StringVar(&tf.dir, "dir", "", "datafile directory (default: OMF if db_create_file_dest is set, else the directory of the container's SYSTEM01.DBF)")
+	fs.BoolVar(&tf.dry_run, "dry-run", false, "print the statements without executing them")
+	return tf
+}
+
+func run_tablespace(args []string) error {
+	return run_group("tablespace", []command{
+		{"create", "CREATE [TEMPORARY|BIGFILE] TABLESPACE with one datafile", run_tablespace_create},
+		{"resize", "resize a datafile or tempfile of a tablespace", run_tablespace_resize},
+		{"add-datafile", "add a datafile or tempfile to a tablespace", run_tablespace_add_datafile},
+		{"autoextend", "set AUTOEXTEND ON/OFF on the files of a tablespace", run_tablespace_autoextend},
+		{"drop", "DROP TABLESPACE INCLUDING CONTENTS [AND DATAFILES]", run_tablespace_drop},
+		{"list", "list tablespaces with their files and sizes", run_tablespace_list},
+	}, args)
+}
+
+// exec_or_print runs stmt, or only prints it with -dry-run.
+func exec_or_print(ctx context.Context, db *sql.DB, dry_run bool, stmt string) error {
+	if dry_run {
+		fmt.Printf("🔎 [dry-run] %s\n", stmt)
+		return nil
+	}
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("%s: %w", stmt, err)
+	}
+	fmt.Printf("✅ %s\n", stmt)
+	return nil
+}
+
+// datafile_layout decides where new files go. Under OMF (db_create_file_dest
+// set) Oracle names the files itself and dir is "". Otherwise the files go
+// next to the container's SYSTEM01.DBF, the same layout go_oracle_003 uses
+// when it creates a PDB.
+func datafile_layout(ctx context.Context, db *sql.DB, override string) (omf bool, dir string, err error) {
+	if override != "" {
+		return false, with_trailing_separator(override), nil
+	}
+
+	var dest sql.NullString
+	if err := db.QueryRowContext(ctx,
+		"SELECT value FROM v$parameter WHERE name = 'db_create_file_dest'").Scan(&dest); err != nil {
+		return false, "", fmt.Errorf("read db_create_file_dest failed: %w", err)
+	}
+	if strings.TrimSpace(dest.String) != "" {
+		fmt.Printf("📁 OMF enabled (db_create_file_dest=%s)\n", dest.String)
+		return true, "", nil
+	}
+
+	q := `
+		SELECT SUBSTR(name, 1, REGEXP_INSTR(name, 'SYSTEM01\.DBF', 1, 1, 0, 'i') - 1)
+		FROM   v$datafile
+		WHERE  REGEXP_LIKE(name, 'SYSTEM01\.DBF', 'i')
+		  AND  con_id = SYS_CONTEXT('USERENV','CON_ID')
+		FETCH FIRST 1 ROWS ONLY`
+	if err := db.QueryRowContext(ctx, q).Scan(&dir); err != nil {
+		return false, "", fmt.Errorf("could not determine datafile directory from SYSTEM01.DBF: %w", err)
+	}
+	dir = with_trailing_separator(dir)
+	fmt.Printf("📁 datafile directory: %s\n", dir)
+	return false, dir, nil
+}
+
+// with_trailing_separator appends the path separator already used in p
+// (backslash on Windows hosts, slash elsewhere).
+func with_trailing_separator(p string) string {
+	sep := "/"
+	if strings.Contains(p, `\`) {
+		sep = `\`
+	}
+	if !strings.HasSuffix(p, sep) {
+		p += sep
+	}
+	return p
+}
+
+// file_clause returns the "'<dir><NAME>NN.DBF' SIZE n" part of a DATAFILE or
+// TEMPFILE clause, or just "SIZE n" under OMF.
+func file_clause(omf bool, dir, name string, seq int, size string) string {
+	if omf {
+		return "SIZE " + size
+	}
+	return fmt.Sprintf("%s SIZE %s", quote_literal(fmt.Sprintf("%s%s%02d.DBF", dir, name, seq)), size)
+}
+
+func autoextend_clause(on bool, next, maxsize string) string {
+	if !on {
+		return "AUTOEXTEND OFF"
+	}
+	return fmt.Sprintf("AUTOEXTEND ON NEXT %s MAXSIZE %s", next, maxsize)
+}
+
+func run_tablespace_create(args []string) error {
+	fs := flag.NewFlagSet("tablespace create", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	tf := add_tablespace_flags(fs)
+	size := fs.String("size", "100M", "initial file size")
+	temporary := fs.Bool("temporary", false, "create a TEMPORARY tablespace")
+	bigfile := fs.Bool("bigfile", false, "create a BIGFILE tablespace")
+	autoextend := fs.Bool("autoextend", true, "AUTOEXTEND ON for the first file")
+	next := fs.String("next", "10M", "AUTOEXTEND NEXT increment")
+	maxsize := fs.String("maxsize", "UNLIMITED", "AUTOEXTEND MAXSIZE")
+	fs.Parse(args)
+
+	name, err := check_identifier("tablespace", tf.name)
+	if err != nil {
+		return err
+	}
+	if *size, err = check_size("size", *size, false); err != nil {
+		return err
+	}
+	if *next, err = check_size("next", *next, false); err != nil {
+		return err
+	}
+	if *maxsize, err = check_size("maxsize", *maxsize, true); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	omf, dir, err := datafile_layout(ctx, db, tf.dir)
+	if err != nil {
+		return err
+	}
+
+	kind, file_kw := "", "DATAFILE"
+	if *bigfile {
+		kind = "BIGFILE "
+	}
+	if *temporary {
+		kind += "TEMPORARY "
+		file_kw = "TEMPFILE"
+	}
+	stmt := fmt.Sprintf("CREATE %sTABLESPACE %s %s %s %s",
+		kind, name, file_kw, file_clause(omf, dir, name, 1, *size), autoextend_clause(*autoextend, *next, *maxsize))
+	return exec_or_print(ctx, db, tf.dry_run, stmt)
+}
+
+// tablespace_file is one row of DBA_DATA_FILES or DBA_TEMP_FILES.
+type tablespace_file struct {
+	tablespace  string
+	contents    string
+	bigfile     string
+	ts_status   string
+	file_id     int
+	file_name   string
+	bytes       int64
+	autoextend  string
+	max_bytes   int64
+	file_status string
+	temporary   bool
+}
+
+// tablespace_files lists the data and temp files of one tablespace, or of
+// all tablespaces when name is "" (a NULL bind). The name is compared
+// exactly: as a LIKE pattern the "_" of APP_DATA would also match APPXDATA.
+func tablespace_files(ctx context.Context, db *sql.DB, name string) ([]tablespace_file, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT t.tablespace_name, t.contents, t.bigfile, t.status,
+		       f.file_id, f.file_name, f.bytes, f.autoextensible, f.maxbytes, f.status, 0
+		FROM   dba_tablespaces t
+		JOIN   dba_data_files f ON f.tablespace_name = t.tablespace_name
+		WHERE  t.tablespace_name = NVL(:1, t.tablespace_name)
+		UNION ALL
+		SELECT t.tablespace_name, t.contents, t.bigfile, t.status,
+		       f.file_id, f.file_name, f.bytes, f.autoextensible, f.maxbytes, f.status, 1
+		FROM   dba_tablespaces t
+		JOIN   dba_temp_files f ON f.tablespace_name = t.tablespace_name
+		WHERE  t.tablespace_name = NVL(:2, t.tablespace_name)
+		ORDER BY 1, 5`, name, name)
+	if err != nil {
+		return nil, fmt.Errorf("list tablespace files failed: %w", err)
+	}
+	defer rows.Close()
+
+	var files []tablespace_file
+	for rows.Next() {
+		var f tablespace_file
+		var temp int
+		if err := rows.Scan(&f.tablespace, &f.contents, &f.bigfile, &f.ts_status,
+			&f.file_id, &f.file_name, &f.bytes, &f.autoextend, &f.max_bytes, &f.file_status, &temp); err != nil {
+			return nil, err
+		}
+		f.temporary = temp == 1
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// pick_files returns the files of a tablespace selected by -file (file id or
+// full name); with an empty selector all files are returned.
+func pick_files(ctx context.Context, db *sql.DB, name, selector string) ([]tablespace_file, error) {
+	files, err := tablespace_files(ctx, db, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("tablespace %s not found or has no files", name)
+	}
+	if selector == "" {
+		return files, nil
+	}
+	id, id_err := strconv.Atoi(selector)
+	for _, f := range files {
+		if (id_err == nil && f.file_id == id) || strings.EqualFold(f.file_name, selector) {
+			return []tablespace_file{f}, nil
+		}
+	}
+	return nil, fmt.Errorf("tablespace %s has no file %q", name, selector)
+}
+
+func file_keyword(f tablespace_file) string {
+	if f.temporary {
+		return "TEMPFILE"
+	}
+	return "DATAFILE"
+}
+
+func run_tablespace_resize(args []string) error {
+	fs := flag.NewFlagSet("tablespace resize", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	tf := add_tablespace_flags(fs)
+	file := fs.String("file", "", "file id or file name (required when the tablespace has several files)")
+	size := fs.String("size", "", "new file size")
+	fs.Parse(args)
+
+	name, err := check_identifier("tablespace", tf.name)
+	if err != nil {
+		return err
+	}
+	if *size, err = check_size("size", *size, false); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	files, err := pick_files(ctx, db, name, *file)
+	if err != nil {
+		return err
+	}
+	if len(files) > 1 {
+		return fmt.Errorf("tablespace %s has %d files; choose one with -file", name, len(files))
+	}
+	f := files[0]
+	stmt := fmt.Sprintf("ALTER DATABASE %s %s RESIZE %s", file_keyword(f), quote_literal(f.file_name), *size)
+	return exec_or_print(ctx, db, tf.dry_run, stmt)
+}
+
+func run_tablespace_add_datafile(args []string) error {
+	fs := flag.NewFlagSet("tablespace add-datafile", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	tf := add_tablespace_flags(fs)
+	size := fs.String("size", "100M", "file size")
+	autoextend := fs.Bool("autoextend", true, "AUTOEXTEND ON for the new file")
+	next := fs.String("next", "10M", "AUTOEXTEND NEXT increment")
+	maxsize := fs.String("maxsize", "UNLIMITED", "AUTOEXTEND MAXSIZE")
+	fs.Parse(args)
+
+	name, err := check_identifier("tablespace", tf.name)
+	if err != nil {
+		return err
+	}
+	if *size, err = check_size("size", *size, false); err != nil {
+		return err
+	}
+	if *next, err = check_size("next", *next, false); err != nil {
+		return err
+	}
+	if *maxsize, err = check_size("maxsize", *maxsize, true); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	files, err := pick_files(ctx, db, name, "")
+	if err != nil {
+		return err
+	}
+	if files[0].bigfile == "YES" {
+		return fmt.Errorf("tablespace %s is a BIGFILE tablespace; use resize instead", name)
+	}
+
+	omf, dir, err := datafile_layout(ctx, db, tf.dir)
+	if err != nil {
+		return err
+	}
+
+	// next free NN in <NAME>NN.DBF
+	seq := len(files) + 1
+	for taken := true; taken; {
+		taken = false
+		candidate := fmt.Sprintf("%s%02d.DBF", name, seq)
+		for _, f := range files {
+			if strings.HasSuffix(strings.ToUpper(f.file_name), candidate) {
+				taken = true
+				seq++
+				break
+			}
+		}
+	}
+
+	kw := "DATAFILE"
+	if files[0].temporary {
+		kw = "TEMPFILE"
+	}
+	stmt := fmt.Sprintf("ALTER TABLESPACE %s ADD %s %s %s",
+		name, kw, file_clause(omf, dir, name, seq, *size), autoextend_clause(*autoextend, *next, *maxsize))
+	return exec_or_print(ctx, db, tf.dry_run, stmt)
+}
+
+func run_tablespace_autoextend(args []string) error {
+	fs := flag.NewFlagSet("tablespace autoextend", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	tf := add_tablespace_flags(fs)
+	file := fs.String("file", "", "file id or file name (default: every file of the tablespace)")
+	on := fs.Bool("on", true, "AUTOEXTEND ON (use -on=false for OFF)")
+	next := fs.String("next", "10M", "AUTOEXTEND NEXT increment")
+	maxsize := fs.String("maxsize", "UNLIMITED", "AUTOEXTEND MAXSIZE")
+	fs.Parse(args)
+
+	name, err := check_identifier("tablespace", tf.name)
+	if err != nil {
+		return err
+	}
+	if *next, err = check_size("next", *next, false); err != nil {
+		return err
+	}
+	if *maxsize, err = check_size("maxsize", *maxsize, true); err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	files, err := pick_files(ctx, db, name, *file)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		stmt := fmt.Sprintf("ALTER DATABASE %s %s %s",
+			file_keyword(f), quote_literal(f.file_name), autoextend_clause(*on, *next, *maxsize))
+		if err := exec_or_print(ctx, db, tf.dry_run, stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func run_tablespace_drop(args []string) error {
+	fs := flag.NewFlagSet("tablespace drop", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	tf := add_tablespace_flags(fs)
+	keep_files := fs.Bool("keep-files", false, "drop the tablespace but leave its files on disk")
+	cascade := fs.Bool("cascade", false, "add CASCADE CONSTRAINTS")
+	fs.Parse(args)
+
+	name, err := check_identifier("tablespace", tf.name)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	stmt := "DROP TABLESPACE " + name + " INCLUDING CONTENTS"
+	if !*keep_files {
+		stmt += " AND DATAFILES"
+	}
+	if *cascade {
+		stmt += " CASCADE CONSTRAINTS"
+	}
+	return exec_or_print(ctx, db, tf.dry_run, stmt)
+}
+
+func run_tablespace_list(args []string) error {
+	fs := flag.NewFlagSet("tablespace list", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	name := fs.String("name", "", "only this tablespace")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	files, err := tablespace_files(ctx, db, strings.ToUpper(*name))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%-20s %-10s %-7s %5s %10s %-4s %10s  %s\n",
+		"TABLESPACE", "CONTENTS", "BIGFILE", "ID", "MB", "AUTO", "MAX_MB", "FILE_NAME")
+	for _, f := range files {
+		fmt.Printf("%-20s %-10s %-7s %5d %10d %-4s %10d  %s\n",
+			f.tablespace, f.contents, f.bigfile, f.file_id, f.bytes>>20, f.autoextend, f.max_bytes>>20, f.file_name)
+	}
+	fmt.Printf("📊 %d file(s)\n", len(files))
+	return nil
+}
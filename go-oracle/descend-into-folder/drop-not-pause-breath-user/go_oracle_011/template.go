@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	template_extension      = ".tmpl"
+	default_template_corpus = "testdata/templates"
+)
+
+// template_context is what a .tmpl file sees when it is rendered:
+//
+//	{{.Username}}   schema the code is deployed into
+//	{{.Container}}  current container, {{.Cdb}} the CDB name
+//	{{.Timestamp}}  start of the run (a time.Time: {{.Timestamp.Format "20060102"}})
+//	{{.Vars.name}}  vars of oracle_connection, then of -vars-profile, then -var name=value
+//
+// Missing keys are errors, so a typo in {{.Vars.tablespace}} fails the
+// render instead of producing "<no value>" in the DDL.
+type template_context struct {
+	Username  string
+	Container string
+	Cdb       string
+	Timestamp time.Time
+	Vars      map[string]string
+}
+
+// template_funcs are the helpers available in templates:
+//
+//	{{ident .Vars.role}}     a simple identifier, upper-cased; anything else fails
+//	{{quote .Username}}      a double-quoted identifier, used exactly as given;
+//	                         names Oracle cannot quote (", NUL, too long) fail
+//	{{literal .Vars.label}}  a single-quoted string literal
+var template_funcs = template.FuncMap{
+	"ident": func(s string) (string, error) {
+		return check_identifier("identifier", s)
+	},
+	"quote":   quote_template_identifier,
+	"literal": quote_literal,
+}
+
+// quote_template_identifier double-quotes s. Oracle quoted identifiers
+// cannot contain " or NUL, so rather than render DDL that fails when it is
+// run, such names fail the render, as do empty and over-long ones.
+func quote_template_identifier(s string) (string, error) {
+	if s == "" || strings.ContainsAny(s, "\"\x00") || len(s) > MAX_IDENTIFIER_LEN {
+		return "", fmt.Errorf("invalid quoted identifier %q", s)
+	}
+	return quote_identifier(s), nil
+}
+
+// var_flags collects repeated -var name=value flags. Unlike -define the
+// names keep their case, as they are looked up as {{.Vars.name}}.
+type var_flags map[string]string
+
+func (v var_flags) String() string { return fmt.Sprint(map[string]string(v)) }
+
+func (v var_flags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || strings.TrimSpace(name) == "" {
+		return fmt.Errorf("expected name=value, got %q", s)
+	}
+	v[strings.TrimSpace(name)] = value
+	return nil
+}
+
+// template_flags are the flags of commands that render .tmpl files.
+type template_flags struct {
+	vars    var_flags
+	profile string
+}
+
+func add_template_flags(fs *flag.FlagSet) *template_flags {
+	tf := &template_flags{vars: var_flags{}}
+	fs.Var(tf.vars, "var", "template variable name=value for {{.Vars.name}} (repeatable)")
+	fs.StringVar(&tf.profile, "vars-profile", "", "connection profile whose vars override those of oracle_connection")
+	return tf
+}
+
+// context builds the template context of a run from the config file and
+// the flags. Container and Cdb are taken from the session by connect; until
+// then Container is the -container flag and Cdb is empty.
+func (tf *template_flags) context(cf *conn_flags, username string) (*template_context, error) {
+	tc := &template_context{Username: username, Container: cf.container, Timestamp: time.Now(), Vars: map[string]string{}}
+	if tc.Container == "" {
+		tc.Container = "CDB$ROOT"
+	}
+	cfg, err := load_config(cf.config_path)
+	if err != nil && (tf.profile != "" || !os.IsNotExist(err)) {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	if cfg != nil {
+		for k, v := range cfg.Oracle_connection.Vars {
+			tc.Vars[k] = v
+		}
+		if tf.profile != "" {
+			oc, err := cfg.profile(tf.profile)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range oc.Vars {
+				tc.Vars[k] = v
+			}
+		}
+	}
+	for k, v := range tf.vars {
+		tc.Vars[k] = v
+	}
+	return tc, nil
+}
+
+// connect fills Container and Cdb from the session.
+func (tc *template_context) connect(ctx context.Context, db *sql.DB) error {
+	if err := db.QueryRowContext(ctx,
+		"SELECT SYS_CONTEXT('USERENV','CON_NAME'), (SELECT name FROM v$database) FROM dual").Scan(&tc.Container, &tc.Cdb); err != nil {
+		return fmt.Errorf("could not read container for templates: %w", err)
+	}
+	return nil
+}
+
+func is_template(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), template_extension)
+}
+
+// template_target is the file name a template renders to, e.g. grants.sql
+// for grants.sql.tmpl; other names are returned unchanged.
+func template_target(path string) string {
+	if is_template(path) {
+		return path[:len(path)-len(template_extension)]
+	}
+	return path
+}
+
+// render_template executes text as a text/template. tc is nil when the
+// caller has no run context, which is an error for a template.
+func render_template(name, text string, tc *template_context) (string, error) {
+	if tc == nil {
+		return "", fmt.Errorf("%s: templates are not supported here", name)
+	}
+	t, err := template.New(filepath.Base(name)).Option("missingkey=error").Funcs(template_funcs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, tc); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// read_source reads a file, rendering it first when it is a .tmpl file.
+func read_source(path string, tc *template_context) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !is_template(path) {
+		return string(data), nil
+	}
+	return render_template(path, string(data), tc)
+}
+
+func run_template(args []string) error {
+	return run_group("template", []command{
+		{"render", "print a .tmpl file rendered with the run context (-offline: without connecting)", run_template_render},
+		{"check", "render the corpus templates with a fixed context and compare with their .expected output", run_template_check},
+	}, args)
+}
+
+func run_template_render(args []string) error {
+	fs := flag.NewFlagSet("template render", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	tf := add_template_flags(fs)
+	owner := fs.String("owner", "", "schema the code is deployed into ({{.Username}})")
+	offline := fs.Bool("offline", false, "do not connect; Container is the -container flag and Cdb is empty")
+	positional := parse_interspersed(fs, args)
+	if len(positional) != 1 {
+		return fmt.Errorf("usage: template render [flags] <file.tmpl>")
+	}
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	tc, err := tf.context(cf, target)
+	if err != nil {
+		return err
+	}
+	if !*offline {
+		ctx := context.Background()
+		db, err := cf.open(ctx)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if err := tc.connect(ctx, db); err != nil {
+			return err
+		}
+	}
+
+	text, err := read_source(positional[0], tc)
+	if err != nil {
+		return err
+	}
+	fmt.Print(text)
+	return nil
+}
+
+// check_template_context is the fixed context of the template corpus.
+func check_template_context() *template_context {
+	return &template_context{
+		Username:  "APP",
+		Container: "PDB1",
+		Cdb:       "CDB1",
+		Timestamp: time.Date(2025, 8, 4, 10, 33, 19, 0, time.UTC),
+		Vars:      map[string]string{"tablespace": "APP_DATA", "reader_role": "app_reader", "label": "it's v1", "odd_name": `app"; DROP USER app --`},
+	}
+}
+
+// render_manifest loads a deploy manifest the way deploy does and prints
+// each unit with its rendered test_sql and call_specs.
+func render_manifest(path string, tc *template_context) (string, error) {
+	units, _, err := load_deploy_units(filepath.Dir(path), path, nil, tc)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, u := range units {
+		fmt.Fprintf(&b, "-- %s\n", u.path)
+		if u.test_sql != "" {
+			fmt.Fprintf(&b, "test_sql: %s\n", u.test_sql)
+		}
+		if cs := u.call_specs; cs != nil {
+			fmt.Fprintf(&b, "call_specs: suffix=%q env=%q methods=%q\n", cs.Suffix, cs.Env, cs.Methods)
+			var types []string
+			for k, v := range cs.Types {
+				types = append(types, k+"="+v)
+			}
+			sort.Strings(types)
+			for _, t := range types {
+				fmt.Fprintf(&b, "call_specs type: %s\n", t)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// template_corpus renders every <name>.tmpl, and the strings of every
+// deploy manifest <name>.yaml, with check_template_context and compares the
+// output (or the error) with <name>.expected. TestTemplateCorpus runs the
+// same comparison under go test.
+var template_corpus = corpus_spec{
+	what:    "template",
+	dir:     default_template_corpus,
+	globs:   []string{"*" + template_extension, "*.yaml"},
+	out_ext: ".expected",
+	render: func(path string) (string, error) {
+		if strings.EqualFold(filepath.Ext(path), ".yaml") {
+			return render_manifest(path, check_template_context())
+		}
+		return read_source(path, check_template_context())
+	},
+}
+
+func run_template_check(args []string) error {
+	return run_corpus_check("template check", template_corpus, args)
+}
@@ -0,0 +1,10 @@
+package main
+
+import "testing"
+
+// TestTemplateCorpus renders every <name>.tmpl and deploy manifest
+// <name>.yaml of testdata/templates with check_template_context and compares
+// the output (or the error) with <name>.expected.
+func TestTemplateCorpus(t *testing.T) {
+	test_corpus(t, template_corpus)
+}
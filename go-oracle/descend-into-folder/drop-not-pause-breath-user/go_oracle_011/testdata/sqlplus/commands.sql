@@ -0,0 +1,20 @@
+REM SQL*Plus commands are only recognised at the start of a statement
+SET ECHO ON
+SET SERVEROUTPUT ON SIZE UNLIMITED
+WHENEVER SQLERROR EXIT SQL.SQLCODE
+PROMPT creating objects
+PRO short form
+EXEC dbms_output.put_line('exec;')
+EXECUTE p1
+SET TRANSACTION READ ONLY;
+SELECT 1 FROM dual;
+/
+BEGIN
+  NULL;
+END;
+.
+/
+@other_script.sql
+SELECT prompt FROM t1;
+spool off
+EXIT
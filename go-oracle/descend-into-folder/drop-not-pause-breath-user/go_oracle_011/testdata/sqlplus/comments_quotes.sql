@@ -0,0 +1,19 @@
+/* a block comment; with a semicolon
+   over two lines */
+SELECT 'it''s; fine' AS a, "odd;name" FROM dual;
+SELECT 1 -- trailing comment; not a terminator
+  FROM dual;
+SELECT /* inline; */ 2 FROM dual;
+SELECT q'[brackets ' and ; inside]' FROM dual;
+SELECT nq'{braces ; }' , Q'!bang ;!' FROM dual;
+SELECT 'string spanning
+two lines;' FROM dual;
+SELECT q'<angle
+; still quoted>' FROM dual;
+BEGIN
+  -- only a "/" alone on its line ends the block (even inside a string,
+  -- as in SQL*Plus); a "/" within a line does not
+  dbms_output.put_line('a
+  / b');
+END;
+/
@@ -0,0 +1,14 @@
+-- an apostrophe in a Java // comment or a PL/SQL -- comment leaves the
+-- lexer "inside a string"; the "/" line still ends the block
+CREATE OR REPLACE AND COMPILE JAVA SOURCE NAMED "Quotes" AS
+public class Quotes {
+  // don't stop at the semicolon; this returns a char
+  public static char q() { return '\''; }
+}
+/
+SELECT 1 FROM dual;
+BEGIN
+  NULL; -- it's a no-op
+END;
+/
+SELECT 2 FROM dual;
@@ -0,0 +1,14 @@
+DEFINE owner = app_owner
+DEFINE greeting = 'hello world'
+DEF n=42
+SELECT '&greeting' FROM &owner..t1 WHERE id = &n;
+DEFINE col = dummy
+SELECT &&col, &col FROM dual;
+UNDEFINE n
+SET DEFINE OFF
+SELECT 'AT&T' FROM dual;
+SET DEFINE ON
+PROMPT owner is &owner
+DEFINE suffix = _v2
+CREATE TABLE t&suffix (id NUMBER);
+SELECT 'a & b' FROM dual;
@@ -0,0 +1,32 @@
+-- SQL ends at ";" or at a "/" line; PL/SQL keeps its ";" and ends at "/"
+CREATE TABLE t1 (id NUMBER);
+INSERT INTO t1 VALUES (1); INSERT INTO t1 VALUES (2);
+UPDATE t1
+   SET id = id + 1
+/
+
+CREATE OR REPLACE
+  PROCEDURE p1 AS
+BEGIN
+  UPDATE t1 SET id = 0;
+  COMMIT;
+END p1;
+/
+DECLARE
+  n NUMBER;
+BEGIN
+  SELECT COUNT(*) INTO n FROM t1;
+END;
+/
+CREATE OR REPLACE TYPE point_t AS OBJECT (x NUMBER, y NUMBER);
+/
+CREATE OR REPLACE AND COMPILE JAVA SOURCE NAMED "Hello" AS
+public class Hello {
+  public static String hi() { return "hi;"; }
+}
+/
+CREATE VIEW v1 AS
+SELECT id
+
+  FROM t1;
+SELECT 'no terminator at end of file' FROM dual
@@ -0,0 +1,2 @@
+SELECT 1 FROM dual;
+SELECT &missing FROM dual;
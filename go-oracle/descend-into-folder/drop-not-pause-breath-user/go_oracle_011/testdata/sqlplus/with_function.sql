@@ -0,0 +1,18 @@
+-- 12c inline PL/SQL: the ";" inside the function does not end the query
+WITH FUNCTION double_it(p NUMBER) RETURN NUMBER IS
+BEGIN
+  RETURN p * 2;
+END;
+SELECT double_it(21) AS answer FROM dual
+/
+with
+  procedure log_it is begin null; end;
+  function one return number is begin log_it; return 1; end;
+select one from dual
+/
+-- a plain WITH query still ends at ";"
+WITH t AS (SELECT 1 AS n FROM dual)
+SELECT n FROM t;
+WITH
+  t AS (SELECT 2 AS n FROM dual)
+SELECT n FROM t;
@@ -0,0 +1,5 @@
+CREATE OR REPLACE FUNCTION get_timestamp RETURN VARCHAR2 IS
+BEGIN
+  RETURN TO_CHAR(SYSTIMESTAMP, 'YYYY-MM-DD HH24:MI:SS');
+END;
+/
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+const default_test_dir = "db/tests"
+
+// test_spec is one YAML file of database unit tests. Every case runs after
+// a savepoint that is rolled back afterwards, and the whole file runs in a
+// transaction that is rolled back, so tests leave no data behind. DDL
+// commits implicitly and does not belong in a test.
+type test_spec struct {
+	Setup []string    `yaml:"setup"` // statements run before every case
+	Cases []test_case `yaml:"cases"`
+}
+
+type test_case struct {
+	Name  string       `yaml:"name"`
+	Check test_check   `yaml:",inline"`
+	After []test_check `yaml:"after"` // table-state assertions after the call
+}
+
+// test_check is a statement with bind values and what it must produce.
+//
+// A query (SELECT/WITH) is compared with expect (its first row) or rows
+// (every row, in order). Any other statement is executed; binds written as
+// {out: ~} or {in: value, out: ~} are OUT and IN OUT binds and expect lists
+// their values after the call. Expected values are compared as text: ~
+// matches NULL (as does "", which Oracle stores as NULL) and {not_null:
+// true} matches any other value.
+type test_check struct {
+	Sql          string  `yaml:"sql"`
+	Binds        []any   `yaml:"binds"`
+	Expect       []any   `yaml:"expect"`
+	Rows         [][]any `yaml:"rows"`
+	Expect_error string  `yaml:"expect_error"` // e.g. ORA-20001; the statement must fail with it
+}
+
+// test_result is the outcome of one case for the console, TAP, JUnit and
+// JSON.
+type test_result struct {
+	file     string
+	name     string
+	failure  string // "" when the case passed
+	duration time.Duration
+	output   []output_line
+}
+
+// format_value renders a scanned or expected value for comparison; null is
+// set for NULL.
+func format_value(v any) (s string, null bool) {
+	switch x := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return x, x == ""
+	case []byte:
+		return strings.ToUpper(hex.EncodeToString(x)), len(x) == 0
+	case time.Time:
+		if x.Hour() == 0 && x.Minute() == 0 && x.Second() == 0 && x.Nanosecond() == 0 {
+			return x.Format("2006-01-02"), false
+		}
+		return x.Format("2006-01-02 15:04:05.999999999"), false
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64), false
+	}
+	return fmt.Sprint(v), false
+}
+
+// match_value compares an actual value with an expected one from a spec.
+func match_value(expected, actual any) error {
+	got, got_null := format_value(actual)
+	if m, ok := expected.(map[string]any); ok {
+		if m["not_null"] != true {
+			return fmt.Errorf("unknown expectation %v (use ~ or {not_null: true})", m)
+		}
+		if got_null {
+			return fmt.Errorf("expected a value, got NULL")
+		}
+		return nil
+	}
+	want, want_null := format_value(expected)
+	switch {
+	case want_null && got_null:
+		return nil
+	case want_null:
+		return fmt.Errorf("expected NULL, got %q", got)
+	case got_null:
+		return fmt.Errorf("expected %q, got NULL", want)
+	case want != got:
+		return fmt.Errorf("expected %q, got %q", want, got)
+	}
+	return nil
+}
+
+func match_row(expected, actual []any) error {
+	if len(expected) != len(actual) {
+		return fmt.Errorf("expected %d value(s), got %d", len(expected), len(actual))
+	}
+	for i := range expected {
+		if err := match_value(expected[i], actual[i]); err != nil {
+			return fmt.Errorf("value %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// bind_args turns spec binds into driver arguments; out holds the
+// destinations of OUT binds in order.
+// OUT values are read as text; NULL reads as "", which matches ~.
+func bind_args(binds []any) (args []any, out []*string) {
+	for _, b := range binds {
+		m, ok := b.(map[string]any)
+		if !ok {
+			args = append(args, b)
+			continue
+		}
+		if _, is_out := m["out"]; !is_out {
+			args = append(args, b)
+			continue
+		}
+		dest := new(string)
+		if in, ok := m["in"]; ok {
+			*dest, _ = format_value(in)
+			args = append(args, sql.Out{Dest: dest, In: true})
+		} else {
+			args = append(args, sql.Out{Dest: dest})
+		}
+		out = append(out, dest)
+	}
+	return args, out
+}
+
+// run_check runs one statement of a case inside tx.
+func run_check(ctx context.Context, tx *sql.Tx, c test_check) error {
+	text := strings.TrimSpace(c.Sql)
+	if text == "" {
+		return fmt.Errorf("sql is empty")
+	}
+	args, out := bind_args(c.Binds)
+	rows, err := run_test_statement(ctx, tx, text, args)
+	if c.Expect_error != "" {
+		if err == nil {
+			return fmt.Errorf("expected %s, statement succeeded", c.Expect_error)
+		}
+		if !strings.Contains(err.Error(), c.Expect_error) {
+			return fmt.Errorf("expected %s, got: %v", c.Expect_error, err)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if rows == nil {
+		if c.Rows != nil {
+			return fmt.Errorf("rows is only checked for queries")
+		}
+		if c.Expect == nil {
+			return nil
+		}
+		actual := make([]any, len(out))
+		for i, o := range out {
+			actual[i] = *o
+		}
+		if err := match_row(c.Expect, actual); err != nil {
+			return fmt.Errorf("out binds: %w", err)
+		}
+		return nil
+	}
+
+	if c.Expect != nil {
+		if len(rows) == 0 {
+			return fmt.Errorf("expected a row, query returned none")
+		}
+		return match_row(c.Expect, rows[0])
+	}
+	if c.Rows != nil {
+		if len(c.Rows) != len(rows) {
+			return fmt.Errorf("expected %d row(s), got %d", len(c.Rows), len(rows))
+		}
+		for i := range rows {
+			if err := match_row(c.Rows[i], rows[i]); err != nil {
+				return fmt.Errorf("row %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// run_test_statement runs a query and returns its rows, or executes any
+// other statement and returns nil rows.
+func run_test_statement(ctx context.Context, tx *sql.Tx, text string, args []any) ([][]any, error) {
+	if !query_start_re.MatchString(strip_leading_comments(text)) {
+		_, err := tx.ExecContext(ctx, text, args...)
+		return nil, err
+	}
+	rs, err := tx.QueryContext(ctx, text, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+	cols, err := rs.Columns()
+	if err != nil {
+		return nil, err
+	}
+	rows := [][]any{}
+	for rs.Next() {
+		vals := make([]any, len(cols))
+		ptrs := make([]any, len(cols))
+		for i := range vals {
+			ptrs[i] = &vals[i]
+		}
+		if err := rs.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		rows = append(rows, vals)
+	}
+	return rows, rs.Err()
+}
+
+// run_test_case runs setup, the case and its after checks between a
+// savepoint and the rollback to it. DBMS_OUTPUT is collected after every
+// statement, labelled with the case and the statement.
+func run_test_case(ctx context.Context, tx *sql.Tx, spec *test_spec, tc test_case, label string, output *output_capture) error {
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT go_oracle_test"); err != nil {
+		return fmt.Errorf("savepoint failed: %w", err)
+	}
+	defer tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT go_oracle_test")
+
+	for i, stmt := range spec.Setup {
+		_, err := tx.ExecContext(ctx, stmt)
+		if out_err := output.collect(ctx, tx, fmt.Sprintf("%s setup[%d]", label, i)); out_err != nil && err == nil {
+			err = out_err
+		}
+		if err != nil {
+			return fmt.Errorf("setup %s: %w", ddl_preview(stmt), err)
+		}
+	}
+	err := run_check(ctx, tx, tc.Check)
+	if out_err := output.collect(ctx, tx, label); out_err != nil && err == nil {
+		err = out_err
+	}
+	if err != nil {
+		return err
+	}
+	for i, c := range tc.After {
+		err := run_check(ctx, tx, c)
+		if out_err := output.collect(ctx, tx, fmt.Sprintf("%s after[%d]", label, i)); out_err != nil && err == nil {
+			err = out_err
+		}
+		if err != nil {
+			return fmt.Errorf("after[%d] %s: %w", i, ddl_preview(c.Sql), err)
+		}
+	}
+	return nil
+}
+
+// run_test_file runs every case of a spec in one transaction that is
+// rolled back at the end.
+func run_test_file(ctx context.Context, db *sql.DB, path string, output *output_capture) ([]test_result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec test_spec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var results []test_result
+	for i, tc := range spec.Cases {
+		if tc.Name == "" {
+			tc.Name = fmt.Sprintf("case %d", i+1)
+		}
+		start := time.Now()
+		mark := output.mark()
+		r := test_result{file: path, name: tc.Name}
+		if err := run_test_case(ctx, tx, &spec, tc, path+": "+tc.Name, output); err != nil {
+			r.failure = err.Error()
+			fmt.Printf("❌ %s: %s: %s\n", path, tc.Name, r.failure)
+		} else {
+			fmt.Printf("✅ %s: %s\n", path, tc.Name)
+		}
+		r.duration = time.Since(start)
+		r.output = output.since(mark)
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// test_files expands directories to the .yaml/.yml files they contain.
+func test_files(paths []string) ([]string, error) {
+	var files []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		err = filepath.WalkDir(p, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			ext := strings.ToLower(filepath.Ext(path))
+			if !d.IsDir() && (ext == ".yaml" || ext == ".yml") {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// write_tap writes the results in TAP version 13 format.
+func write_tap(path string, results []test_result) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", len(results))
+	for i, r := range results {
+		if r.failure == "" {
+			fmt.Fprintf(&b, "ok %d - %s: %s\n", i+1, r.file, r.name)
+			continue
+		}
+		fmt.Fprintf(&b, "not ok %d - %s: %s\n  ---\n  message: %s\n  ...\n", i+1, r.file, r.name, strconv.Quote(r.failure))
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+type junit_suites struct {
+	XMLName xml.Name      `xml:"testsuites"`
+	Tests   int           `xml:"tests,attr"`
+	Fails   int           `xml:"failures,attr"`
+	Suites  []junit_suite `xml:"testsuite"`
+}
+
+type junit_suite struct {
+	Name  string       `xml:"name,attr"`
+	Tests int          `xml:"tests,attr"`
+	Fails int          `xml:"failures,attr"`
+	Time  string       `xml:"time,attr"`
+	Cases []junit_case `xml:"testcase"`
+}
+
+type junit_case struct {
+	Name      string         `xml:"name,attr"`
+	Classname string         `xml:"classname,attr"`
+	Time      string         `xml:"time,attr"`
+	Failure   *junit_failure `xml:"failure,omitempty"`
+}
+
+type junit_failure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// write_junit writes the results as JUnit XML, one testsuite per spec file.
+func write_junit(path string, results []test_result) error {
+	out := junit_suites{}
+	index := map[string]int{}
+	durations := map[string]time.Duration{}
+	for _, r := range results {
+		i, ok := index[r.file]
+		if !ok {
+			i = len(out.Suites)
+			index[r.file] = i
+			out.Suites = append(out.Suites, junit_suite{Name: r.file})
+		}
+		s := &out.Suites[i]
+		c := junit_case{Name: r.name, Classname: strings.TrimSuffix(filepath.Base(r.file), filepath.Ext(r.file)),
+			Time: fmt.Sprintf("%.3f", r.duration.Seconds())}
+		if r.failure != "" {
+			c.Failure = &junit_failure{Message: r.failure, Text: r.failure}
+			s.Fails++
+			out.Fails++
+		}
+		s.Cases = append(s.Cases, c)
+		s.Tests++
+		out.Tests++
+		durations[r.file] += r.duration
+	}
+	for i := range out.Suites {
+		out.Suites[i].Time = fmt.Sprintf("%.3f", durations[out.Suites[i].Name].Seconds())
+	}
+	data, err := xml.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append([]byte(xml.Header), append(data, '\n')...), 0o644)
+}
+
+type json_test_result struct {
+	File        string        `json:"file"`
+	Name        string        `json:"name"`
+	Ok          bool          `json:"ok"`
+	Failure     string        `json:"failure,omitempty"`
+	Duration_ms int64         `json:"duration_ms"`
+	Output      []output_line `json:"output,omitempty"`
+}
+
+func json_test_results(results []test_result) []json_test_result {
+	out := []json_test_result{}
+	for _, r := range results {
+		out = append(out, json_test_result{File: r.file, Name: r.name, Ok: r.failure == "", Failure: r.failure,
+			Duration_ms: r.duration.Milliseconds(), Output: r.output})
+	}
+	return out
+}
+
+func run_test(args []string) error {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	cf := add_conn_flags(fs)
+	owner := fs.String("owner", "", "schema the tests run against (CURRENT_SCHEMA)")
+	tap := fs.String("tap", "", "write TAP output to this file")
+	junit := fs.String("junit", "", "write JUnit XML to this file")
+	json_path := fs.String("json", "", "write the results (with DBMS_OUTPUT) as JSON to this file")
+	output := add_output_flags(fs)
+	positional := parse_interspersed(fs, args)
+	if len(positional) == 0 {
+		positional = []string{default_test_dir}
+	}
+
+	target, err := check_identifier("owner", *owner)
+	if err != nil {
+		return fmt.Errorf("-owner is required: %w", err)
+	}
+	files, err := test_files(positional)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return fmt.Errorf("no test specs found in %s", strings.Join(positional, ", "))
+	}
+
+	ctx := context.Background()
+	db, err := cf.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	if _, err := db.ExecContext(ctx, "ALTER SESSION SET CURRENT_SCHEMA = "+target); err != nil {
+		return fmt.Errorf("set current_schema failed: %w", err)
+	}
+	if err := output.enable(ctx, db); err != nil {
+		return err
+	}
+
+	var results []test_result
+	for _, f := range files {
+		rs, err := run_test_file(ctx, db, f, output)
+		if err != nil {
+			return err
+		}
+		results = append(results, rs...)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.failure != "" {
+			failed++
+		}
+	}
+	fmt.Printf("📊 tests passed=%d, failed=%d\n", len(results)-failed, failed)
+	if *tap != "" {
+		if err := write_tap(*tap, results); err != nil {
+			return err
+		}
+		fmt.Printf("📝 TAP written to %s\n", *tap)
+	}
+	if *junit != "" {
+		if err := write_junit(*junit, results); err != nil {
+			return err
+		}
+		fmt.Printf("📝 JUnit XML written to %s\n", *junit)
+	}
+	if *json_path != "" {
+		if err := write_json(*json_path, json_test_results(results)); err != nil {
+			return err
+		}
+		fmt.Printf("📝 JSON results written to %s\n", *json_path)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d test(s) failed", failed)
+	}
+	return nil
+}